@@ -0,0 +1,1249 @@
+package dft
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	apiVersion     = "v1.41"
+	apiDefaultHost = "unix:///var/run/docker.sock"
+
+	streamStderr = 2
+)
+
+// apiRuntime implements `Runtime` by talking to the Docker Engine API directly
+// instead of spawning a `docker` process for every call
+type apiRuntime struct {
+	client *http.Client
+	// dial opens a dedicated connection to the engine, used for streams
+	// that need to be written after the response started (exec stdin)
+	dial func(ctx context.Context) (net.Conn, error)
+	base string
+	// host is the engine address in the format of `DOCKER_HOST`
+	host string
+}
+
+// NewDockerAPIRuntime returns a `Runtime` talking to the Docker Engine API
+// without the need of the `docker` CLI.
+//
+// The host is given in the same format as `DOCKER_HOST`
+// ("unix:///var/run/docker.sock" or "tcp://127.0.0.1:2375").
+// If host is empty, `DOCKER_HOST` is used, falling back to the default socket.
+func NewDockerAPIRuntime(host string) (Runtime, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+
+	if host == "" {
+		host = apiDefaultHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		dial := func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+
+			return d.DialContext(ctx, "unix", socket)
+		}
+
+		return &apiRuntime{
+			client: &http.Client{
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return dial(ctx)
+					},
+				},
+			},
+			dial: dial,
+			// the host is ignored by the dialer but required for a valid URL
+			base: "http://docker",
+			host: host,
+		}, nil
+	case "tcp", "http":
+		addr := u.Host
+
+		return &apiRuntime{
+			client: &http.Client{},
+			dial: func(ctx context.Context) (net.Conn, error) {
+				var d net.Dialer
+
+				return d.DialContext(ctx, "tcp", addr)
+			},
+			base: "http://" + addr,
+			host: "tcp://" + addr,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+	}
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// request sends the request and returns the response if it succeeded.
+// The body is sent as is if it is an `io.Reader` (tar archives),
+// otherwise it is encoded as JSON.
+// The caller is responsible for closing the body.
+func (r *apiRuntime) request(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	body any,
+) (*http.Response, error) {
+	var (
+		rdr         io.Reader
+		contentType string
+	)
+
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		rdr = b
+		contentType = "application/x-tar"
+	default:
+		enc, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		rdr = bytes.NewReader(enc)
+		contentType = "application/json"
+	}
+
+	u := r.base + "/" + apiVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, rdr)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		defer res.Body.Close()
+
+		var apiErr apiError
+
+		b, _ := io.ReadAll(res.Body)
+		if json.Unmarshal(b, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(b))
+		}
+
+		return nil, &statusError{
+			code:    res.StatusCode,
+			message: apiErr.Message,
+		}
+	}
+
+	return res, nil
+}
+
+// call sends the request and decodes the JSON response into out (if not nil)
+func (r *apiRuntime) call(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	body any,
+	out any,
+) error {
+	res, err := r.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, res.Body)
+
+		return err
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// hijack sends the request on a dedicated connection and hands the raw
+// connection to the caller once the engine upgraded it.
+// The response stream has to be read from the returned reader,
+// as it may hold bytes already read from the connection.
+func (r *apiRuntime) hijack(
+	ctx context.Context,
+	path string,
+	body any,
+) (net.Conn, io.Reader, error) {
+	enc, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		r.base+"/"+apiVersion+path,
+		bytes.NewReader(enc),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+
+		return nil, nil, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		defer conn.Close()
+
+		b, _ := io.ReadAll(res.Body)
+
+		var apiErr apiError
+		if json.Unmarshal(b, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(b))
+		}
+
+		return nil, nil, &statusError{
+			code:    res.StatusCode,
+			message: apiErr.Message,
+		}
+	}
+
+	return conn, br, nil
+}
+
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%d %s", e.code, e.message)
+}
+
+func isStatus(err error, code int) bool {
+	var sErr *statusError
+
+	return errors.As(err, &sErr) && sErr.code == code
+}
+
+// isNoSuchImage reports if the engine did not find the image
+func isNoSuchImage(err error) bool {
+	var sErr *statusError
+
+	return errors.As(err, &sErr) &&
+		sErr.code == http.StatusNotFound &&
+		strings.Contains(strings.ToLower(sErr.message), "no such image")
+}
+
+type apiPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type apiMount struct {
+	Type   string `json:"Type"`
+	Name   string `json:"Name,omitempty"`
+	Source string `json:"Source"`
+	Target string `json:"Target"`
+}
+
+type apiCreateRequest struct {
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Env          []string            `json:"Env"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Healthcheck  *apiHealthcheck     `json:"Healthcheck,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	HostConfig   struct {
+		PortBindings map[string][]apiPortBinding `json:"PortBindings"`
+		Mounts       []apiMount                  `json:"Mounts"`
+		NetworkMode  string                      `json:"NetworkMode,omitempty"`
+		CapAdd       []string                    `json:"CapAdd,omitempty"`
+	} `json:"HostConfig"`
+	NetworkingConfig struct {
+		EndpointsConfig map[string]apiEndpoint `json:"EndpointsConfig,omitempty"`
+	} `json:"NetworkingConfig"`
+}
+
+type apiEndpoint struct {
+	Aliases   []string `json:"Aliases,omitempty"`
+	IPAddress string   `json:"IPAddress,omitempty"`
+}
+
+// apiHealthcheck uses nanoseconds for all durations
+type apiHealthcheck struct {
+	Test        []string `json:"Test"`
+	Interval    int64    `json:"Interval,omitempty"`
+	Timeout     int64    `json:"Timeout,omitempty"`
+	Retries     int      `json:"Retries,omitempty"`
+	StartPeriod int64    `json:"StartPeriod,omitempty"`
+}
+
+type apiInspectResponse struct {
+	State struct {
+		Status string         `json:"Status"`
+		Health *inspectHealth `json:"Health"`
+	} `json:"State"`
+	Mounts []struct {
+		Type string `json:"Type"`
+		Name string `json:"Name"`
+	} `json:"Mounts"`
+	NetworkSettings struct {
+		Ports    map[string][]apiPortBinding `json:"Ports"`
+		Networks map[string]apiEndpoint      `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func (r *apiRuntime) Create(ctx context.Context, cfg RunConfig) (string, error) {
+	body := apiCreateRequest{
+		Image:        cfg.Image,
+		Cmd:          cfg.Cmd,
+		Env:          cfg.Env,
+		ExposedPorts: map[string]struct{}{},
+		Labels:       cfg.Labels,
+	}
+	body.HostConfig.PortBindings = map[string][]apiPortBinding{}
+	body.HostConfig.CapAdd = cfg.CapAdd
+
+	for i := range cfg.Ports {
+		key := strconv.FormatUint(uint64(cfg.Ports[i][0]), base10) + "/tcp"
+
+		hostPort := ""
+		if cfg.Ports[i][1] != 0 {
+			hostPort = strconv.FormatUint(uint64(cfg.Ports[i][1]), base10)
+		}
+
+		body.ExposedPorts[key] = struct{}{}
+		body.HostConfig.PortBindings[key] = append(
+			body.HostConfig.PortBindings[key],
+			apiPortBinding{HostPort: hostPort},
+		)
+	}
+
+	if cfg.Network != "" {
+		body.HostConfig.NetworkMode = cfg.Network
+		body.NetworkingConfig.EndpointsConfig = map[string]apiEndpoint{
+			cfg.Network: {Aliases: cfg.NetworkAliases},
+		}
+	}
+
+	if cfg.NoHealthCheck {
+		body.Healthcheck = &apiHealthcheck{Test: []string{"NONE"}}
+	}
+
+	if hc := cfg.HealthCheck; hc != nil {
+		body.Healthcheck = &apiHealthcheck{
+			Test:        []string{"CMD-SHELL", hc.Cmd},
+			Interval:    int64(hc.Interval),
+			Timeout:     int64(hc.Timeout),
+			Retries:     hc.Retries,
+			StartPeriod: int64(hc.StartPeriod),
+		}
+	}
+
+	for i := range cfg.Mounts {
+		body.HostConfig.Mounts = append(
+			body.HostConfig.Mounts,
+			apiMount{
+				Type:   "bind",
+				Source: cfg.Mounts[i][0],
+				Target: cfg.Mounts[i][1],
+			},
+		)
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+
+	var query url.Values
+	if cfg.Name != "" {
+		query = url.Values{"name": {cfg.Name}}
+	}
+
+	err := r.call(ctx, http.MethodPost, "/containers/create", query, body, &created)
+	if isNoSuchImage(err) && cfg.NoPull {
+		return "", fmt.Errorf("unable to create container: %w: %s", ErrImageNotPresent, cfg.Image)
+	}
+
+	// INFO: other resources (e.g. networks) are reported as 404 as well
+	if isNoSuchImage(err) {
+		// `docker create` pulls missing images implicitly, so do we
+		if err = r.Pull(ctx, cfg.Image, io.Discard); err != nil {
+			return "", fmt.Errorf("unable to create container: %w", err)
+		}
+
+		err = r.call(ctx, http.MethodPost, "/containers/create", query, body, &created)
+	}
+
+	if isStatus(err, http.StatusConflict) {
+		return "", fmt.Errorf("unable to create container: %w: %w", ErrNameConflict, err)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("unable to create container: %w", err)
+	}
+
+	if len(created.ID) < idLength {
+		return "", fmt.Errorf("unable to read container id: %q", created.ID)
+	}
+
+	return created.ID[:idLength], nil
+}
+
+func (r *apiRuntime) Find(
+	ctx context.Context,
+	labels map[string]string,
+) ([]string, error) {
+	filter := make([]string, 0, len(labels))
+
+	for _, k := range sortedKeys(labels) {
+		filter = append(filter, k+"="+labels[k])
+	}
+
+	filters, err := json.Marshal(map[string][]string{"label": filter})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []struct {
+		ID string `json:"Id"`
+	}
+
+	err = r.call(
+		ctx,
+		http.MethodGet,
+		"/containers/json",
+		url.Values{"all": {"true"}, "filters": {string(filters)}},
+		nil,
+		&found,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers: %w", err)
+	}
+
+	ids := make([]string, 0, len(found))
+
+	for i := range found {
+		ids = append(ids, found[i].ID[:min(len(found[i].ID), idLength)])
+	}
+
+	return ids, nil
+}
+
+func (r *apiRuntime) Start(ctx context.Context, id string) error {
+	err := r.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Pull(ctx context.Context, ref string, w io.Writer) error {
+	name, tag := splitImageRef(ref)
+
+	res, err := r.request(
+		ctx,
+		http.MethodPost,
+		"/images/create",
+		url.Values{"fromImage": {name}, "tag": {tag}},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to pull image %s: %w", ref, err)
+	}
+	defer res.Body.Close()
+
+	// errors during the pull are reported inside of the progress stream
+	err = decodeProgress(res.Body, w)
+	if err != nil {
+		return fmt.Errorf("unable to pull image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) ImageExists(ctx context.Context, ref string) (bool, error) {
+	err := r.call(ctx, http.MethodGet, "/images/"+ref+"/json", nil, nil, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("unable to inspect image %s: %w", ref, err)
+	}
+
+	return true, nil
+}
+
+func (r *apiRuntime) inspect(ctx context.Context, id string) (apiInspectResponse, error) {
+	var res apiInspectResponse
+
+	err := r.call(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &res)
+	if err != nil {
+		return res, fmt.Errorf("unable to inspect container: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *apiRuntime) Inspect(ctx context.Context, id string) (string, error) {
+	res, err := r.request(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to inspect container: %w", err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to inspect container: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func (r *apiRuntime) State(ctx context.Context, id string) (string, error) {
+	res, err := r.inspect(ctx, id)
+	if isStatus(err, http.StatusNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrContainerNotFound, id)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return res.State.Status, nil
+}
+
+func (r *apiRuntime) Ports(ctx context.Context, id string) (map[uint][]string, error) {
+	res, err := r.inspect(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve container ports: %w", err)
+	}
+
+	portMappings := map[uint][]string{}
+
+	for key, bindings := range res.NetworkSettings.Ports {
+		internal, _, _ := strings.Cut(key, "/")
+
+		port, pErr := strconv.ParseUint(internal, base10, bit64)
+		if pErr != nil {
+			return nil, pErr
+		}
+
+		for i := range bindings {
+			portMappings[uint(port)] = append(
+				portMappings[uint(port)],
+				net.JoinHostPort(bindings[i].HostIP, bindings[i].HostPort),
+			)
+		}
+	}
+
+	return portMappings, nil
+}
+
+func (r *apiRuntime) Logs(
+	ctx context.Context,
+	id string,
+	opts LogOptions,
+) (string, error) {
+	res, err := r.request(
+		ctx,
+		http.MethodGet,
+		"/containers/"+id+"/logs",
+		logQuery(opts),
+		nil,
+	)
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to retrieve logs for container %s: %w",
+			id,
+			err,
+		)
+	}
+	defer res.Body.Close()
+
+	var out bytes.Buffer
+
+	err = demuxStream(res.Body, &out, &out)
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to retrieve logs for container %s: %w",
+			id,
+			err,
+		)
+	}
+
+	return out.String(), nil
+}
+
+// logQuery returns the query of the logs endpoint selecting the logs
+// of the options
+func logQuery(opts LogOptions) url.Values {
+	stdOut, stdErr := opts.streams()
+
+	query := url.Values{
+		"stdout": {strconv.FormatBool(stdOut)},
+		"stderr": {strconv.FormatBool(stdErr)},
+	}
+
+	if opts.Tail > 0 {
+		query.Set("tail", strconv.Itoa(opts.Tail))
+	}
+
+	if !opts.Since.IsZero() {
+		query.Set("since", unixTimestamp(opts.Since))
+	}
+
+	if !opts.Until.IsZero() {
+		query.Set("until", unixTimestamp(opts.Until))
+	}
+
+	if opts.Timestamps {
+		query.Set("timestamps", "1")
+	}
+
+	return query
+}
+
+func (r *apiRuntime) FollowLogs(
+	ctx context.Context,
+	id string,
+	opts LogOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	query := logQuery(opts)
+	query.Set("follow", "1")
+
+	res, err := r.request(
+		ctx,
+		http.MethodGet,
+		"/containers/"+id+"/logs",
+		query,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to follow logs for container %s: %w",
+			id,
+			err,
+		)
+	}
+	defer res.Body.Close()
+
+	err = demuxStream(res.Body, stdOut, stdErr)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to follow logs for container %s: %w",
+			id,
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Exec(
+	ctx context.Context,
+	id string,
+	cmd []string,
+	opts ExecOptions,
+) (int, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+
+	err := r.call(
+		ctx,
+		http.MethodPost,
+		"/containers/"+id+"/exec",
+		nil,
+		map[string]any{
+			"AttachStdin":  opts.Stdin != nil,
+			"AttachStdout": true,
+			"AttachStderr": true,
+			"Tty":          opts.TTY,
+			"User":         opts.User,
+			"WorkingDir":   opts.WorkDir,
+			"Env":          opts.Env,
+			"Cmd":          cmd,
+		},
+		&created,
+	)
+	if err != nil {
+		return -1, fmt.Errorf("unable to create exec: %w", err)
+	}
+
+	stdOut, stdErr := opts.Stdout, opts.Stderr
+	if stdOut == nil {
+		stdOut = io.Discard
+	}
+
+	if stdErr == nil {
+		stdErr = io.Discard
+	}
+
+	start := map[string]any{
+		"Detach": false,
+		"Tty":    opts.TTY,
+	}
+
+	if opts.Stdin != nil {
+		err = r.execStdin(ctx, created.ID, start, opts, stdOut, stdErr)
+	} else {
+		err = r.execStart(ctx, created.ID, start, opts, stdOut, stdErr)
+	}
+
+	if err != nil {
+		return -1, err
+	}
+
+	var inspected struct {
+		ExitCode int  `json:"ExitCode"`
+		Running  bool `json:"Running"`
+	}
+
+	err = r.call(
+		ctx,
+		http.MethodGet,
+		"/exec/"+created.ID+"/json",
+		nil,
+		nil,
+		&inspected,
+	)
+	if err != nil {
+		return -1, fmt.Errorf("unable to inspect exec: %w", err)
+	}
+
+	if inspected.ExitCode != 0 {
+		err = fmt.Errorf("exit status %d", inspected.ExitCode)
+	}
+
+	return inspected.ExitCode, err
+}
+
+// execStart starts the exec and copies its output until it exits
+func (r *apiRuntime) execStart(
+	ctx context.Context,
+	execID string,
+	start map[string]any,
+	opts ExecOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	res, err := r.request(
+		ctx,
+		http.MethodPost,
+		"/exec/"+execID+"/start",
+		nil,
+		start,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to start exec: %w", err)
+	}
+	defer res.Body.Close()
+
+	return copyExecOutput(res.Body, opts.TTY, stdOut, stdErr)
+}
+
+// execStdin starts the exec on a hijacked connection, streams stdin into it
+// and copies its output until it exits
+func (r *apiRuntime) execStdin(
+	ctx context.Context,
+	execID string,
+	start map[string]any,
+	opts ExecOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	conn, rdr, err := r.hijack(ctx, "/exec/"+execID+"/start", start)
+	if err != nil {
+		return fmt.Errorf("unable to start exec: %w", err)
+	}
+	defer conn.Close()
+
+	// the connection does not follow the context after the upgrade
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	go func() {
+		_, _ = io.Copy(conn, opts.Stdin)
+
+		// INFO: only closing the write side lets the command see EOF
+		// while its output can still be read
+		if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		}
+	}()
+
+	err = copyExecOutput(rdr, opts.TTY, stdOut, stdErr)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// copyExecOutput copies the exec output, which is only multiplexed without TTY
+func copyExecOutput(
+	r io.Reader,
+	tty bool,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	if tty {
+		_, err := io.Copy(stdOut, r)
+
+		return err
+	}
+
+	return demuxStream(r, stdOut, stdErr)
+}
+
+func (r *apiRuntime) Health(ctx context.Context, id string) (Health, error) {
+	res, err := r.inspect(ctx, id)
+	if err != nil {
+		return Health{}, err
+	}
+
+	return res.State.Health.health(), nil
+}
+
+func (r *apiRuntime) CopyTo(
+	ctx context.Context,
+	id string,
+	dir string,
+	archive io.Reader,
+) error {
+	res, err := r.request(
+		ctx,
+		http.MethodPut,
+		"/containers/"+id+"/archive",
+		url.Values{"path": {dir}},
+		archive,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to copy to %s: %w", dir, err)
+	}
+
+	return res.Body.Close()
+}
+
+func (r *apiRuntime) CopyFrom(
+	ctx context.Context,
+	id string,
+	path string,
+) (io.ReadCloser, error) {
+	res, err := r.request(
+		ctx,
+		http.MethodGet,
+		"/containers/"+id+"/archive",
+		url.Values{"path": {path}},
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy from %s: %w", path, err)
+	}
+
+	return res.Body, nil
+}
+
+func (r *apiRuntime) Stop(ctx context.Context, id string) error {
+	err := r.call(ctx, http.MethodPost, "/containers/"+id+"/stop", nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to stop container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Pause(ctx context.Context, id string) error {
+	err := r.call(ctx, http.MethodPost, "/containers/"+id+"/pause", nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to pause container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Unpause(ctx context.Context, id string) error {
+	err := r.call(ctx, http.MethodPost, "/containers/"+id+"/unpause", nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to unpause container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Restart(
+	ctx context.Context,
+	id string,
+	timeout time.Duration,
+) error {
+	err := r.call(
+		ctx,
+		http.MethodPost,
+		"/containers/"+id+"/restart",
+		url.Values{"t": {timeoutSeconds(timeout)}},
+		nil,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to restart container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Kill(
+	ctx context.Context,
+	id string,
+	signal syscall.Signal,
+) error {
+	err := r.call(
+		ctx,
+		http.MethodPost,
+		"/containers/"+id+"/kill",
+		url.Values{"signal": {strconv.Itoa(int(signal))}},
+		nil,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to kill container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Remove(ctx context.Context, id string) error {
+	err := r.call(ctx, http.MethodDelete, "/containers/"+id, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to remove container: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) Volumes(ctx context.Context, id string) ([]string, error) {
+	res, err := r.inspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []string{}
+
+	for i := range res.Mounts {
+		if res.Mounts[i].Type == "volume" && res.Mounts[i].Name != "" {
+			volumes = append(volumes, res.Mounts[i].Name)
+		}
+	}
+
+	return volumes, nil
+}
+
+func (r *apiRuntime) RemoveVolumes(ctx context.Context, names []string) error {
+	for i := range names {
+		err := r.call(ctx, http.MethodDelete, "/volumes/"+names[i], nil, nil, nil)
+		if err != nil {
+			return fmt.Errorf("unable to remove volume %s: %w", names[i], err)
+		}
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) CreateNetwork(
+	ctx context.Context,
+	name string,
+	labels map[string]string,
+) error {
+	err := r.call(
+		ctx,
+		http.MethodPost,
+		"/networks/create",
+		nil,
+		map[string]any{"Name": name, "CheckDuplicate": true, "Labels": labels},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create network: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	err := r.call(ctx, http.MethodDelete, "/networks/"+name, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to remove network: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) ConnectNetwork(
+	ctx context.Context,
+	network string,
+	id string,
+	aliases []string,
+) error {
+	err := r.call(
+		ctx,
+		http.MethodPost,
+		"/networks/"+network+"/connect",
+		nil,
+		map[string]any{
+			"Container":      id,
+			"EndpointConfig": apiEndpoint{Aliases: aliases},
+		},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to connect container to network: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) DisconnectNetwork(
+	ctx context.Context,
+	network string,
+	id string,
+) error {
+	err := r.call(
+		ctx,
+		http.MethodPost,
+		"/networks/"+network+"/disconnect",
+		nil,
+		map[string]any{"Container": id},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to disconnect container from network: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) IPAddress(
+	ctx context.Context,
+	id string,
+	network string,
+) (string, error) {
+	res, err := r.inspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	n, ok := res.NetworkSettings.Networks[network]
+	if !ok {
+		return "", fmt.Errorf("container is not connected to network %s", network)
+	}
+
+	return n.IPAddress, nil
+}
+
+func (r *apiRuntime) Build(ctx context.Context, cfg BuildConfig, w io.Writer) error {
+	args, err := json.Marshal(cfg.Args)
+	if err != nil {
+		return err
+	}
+
+	labels, err := json.Marshal(cfg.Labels)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"t":          {cfg.Tag},
+		"dockerfile": {cfg.Dockerfile},
+		"buildargs":  {string(args)},
+		"labels":     {string(labels)},
+		"rm":         {"1"},
+		"forcerm":    {"1"},
+	}
+
+	if cfg.Target != "" {
+		query.Set("target", cfg.Target)
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeTar(pw, cfg.Context, "", 0))
+	}()
+	defer pr.Close()
+
+	res, err := r.request(ctx, http.MethodPost, "/build", query, pr)
+	if err != nil {
+		return fmt.Errorf("unable to build image: %w", err)
+	}
+	defer res.Body.Close()
+
+	err = decodeProgress(res.Body, w)
+	if err != nil {
+		return fmt.Errorf("unable to build image: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiRuntime) RemoveImage(ctx context.Context, ref string) error {
+	err := r.call(ctx, http.MethodDelete, "/images/"+ref, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to remove image: %w", err)
+	}
+
+	return nil
+}
+
+// decodeProgress writes the messages of a JSON progress stream (pull, build)
+// into w and returns the first error reported inside of the stream
+func decodeProgress(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+
+	for {
+		var msg struct {
+			Stream   string `json:"stream"`
+			Status   string `json:"status"`
+			Progress string `json:"progress"`
+			ID       string `json:"id"`
+			Error    string `json:"error"`
+		}
+
+		err := dec.Decode(&msg)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if msg.Error != "" {
+			_, _ = io.WriteString(w, msg.Error+"\n")
+
+			return errors.New(msg.Error)
+		}
+
+		switch {
+		case msg.Stream != "":
+			_, _ = io.WriteString(w, msg.Stream)
+		case msg.Status != "":
+			line := msg.Status
+			if msg.ID != "" {
+				line = msg.ID + ": " + line
+			}
+
+			if msg.Progress != "" {
+				line += " " + msg.Progress
+			}
+
+			_, _ = io.WriteString(w, line+"\n")
+		}
+	}
+}
+
+// demuxStream splits the multiplexed stdout/stderr stream the engine sends
+// for containers without a TTY.
+// Each frame starts with an 8 byte header: [STREAM, 0, 0, 0, SIZE (uint32 BE)].
+func demuxStream(r io.Reader, stdOut io.Writer, stdErr io.Writer) error {
+	header := make([]byte, 8)
+
+	for {
+		_, err := io.ReadFull(r, header)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		w := stdOut
+		if header[0] == streamStderr {
+			w = stdErr
+		}
+
+		_, err = io.CopyN(w, r, int64(binary.BigEndian.Uint32(header[4:])))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unixTimestamp formats t as "<seconds>.<nanoseconds>" like the API expects
+func unixTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// splitImageRef splits an image reference into name and tag (or digest),
+// defaulting to "latest" since the API would pull all tags otherwise
+func splitImageRef(ref string) (string, string) {
+	if name, digest, ok := strings.Cut(ref, "@"); ok {
+		return name, digest
+	}
+
+	i := strings.LastIndex(ref, ":")
+	if i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+
+	return ref, "latest"
+}
@@ -0,0 +1,479 @@
+package dft_test
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+)
+
+// fakeEngine is a minimal Docker Engine API served on a unix socket
+type fakeEngine struct {
+	mu      sync.Mutex
+	pulled  bool
+	state   string
+	removed bool
+	built   []string
+}
+
+func (e *fakeEngine) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	writeFrame := func(w io.Writer, stream byte, s string) {
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(s)))
+
+		_, _ = w.Write(header)
+		_, _ = w.Write([]byte(s))
+	}
+
+	mux.HandleFunc(
+		"POST /v1.41/containers/create",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			var body struct {
+				HostConfig struct {
+					NetworkMode string
+				}
+			}
+
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			if network := body.HostConfig.NetworkMode; network != "" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"network ` + network + ` not found"}`))
+
+				return
+			}
+
+			if !e.pulled {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"No such image: mongo:7-jammy"}`))
+
+				return
+			}
+
+			e.state = "created"
+
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id":"0123456789abcdef0123"}`))
+		},
+	)
+	mux.HandleFunc(
+		"POST /v1.41/images/create",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			if r.URL.Query().Get("tag") != "7-jammy" {
+				_, _ = w.Write([]byte(`{"error":"unexpected tag"}`))
+
+				return
+			}
+
+			e.pulled = true
+
+			_, _ = w.Write([]byte(`{"status":"Pulling from library/mongo"}`))
+		},
+	)
+	mux.HandleFunc(
+		"POST /v1.41/containers/{id}/start",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			e.state = "running"
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	mux.HandleFunc(
+		"POST /v1.41/containers/{id}/stop",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			e.state = "exited"
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	mux.HandleFunc(
+		"DELETE /v1.41/containers/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			e.removed = true
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	mux.HandleFunc(
+		"DELETE /v1.41/volumes/{name}",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	mux.HandleFunc(
+		"GET /v1.41/containers/{id}/json",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			if e.removed {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"No such container"}`))
+
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"State": map[string]any{"Status": e.state},
+				"Mounts": []map[string]any{
+					{"Type": "volume", "Name": "anon"},
+					{"Type": "bind", "Source": "/tmp"},
+				},
+				"NetworkSettings": map[string]any{
+					"Ports": map[string]any{
+						"27017/tcp": []map[string]any{
+							{"HostIp": "0.0.0.0", "HostPort": "32768"},
+							{"HostIp": "::", "HostPort": "32768"},
+						},
+						"9999/tcp": nil,
+					},
+				},
+			})
+		},
+	)
+	mux.HandleFunc(
+		"GET /v1.41/containers/{id}/logs",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			if e.removed {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"No such container"}`))
+
+				return
+			}
+
+			writeFrame(w, 1, "waiting for connections\n")
+			writeFrame(w, 2, "warning\n")
+		},
+	)
+	mux.HandleFunc(
+		"POST /v1.41/build",
+		func(w http.ResponseWriter, r *http.Request) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			// record the entries of the build context, links with their target
+			tr := tar.NewReader(r.Body)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					break
+				}
+
+				entry := hdr.Name
+				if hdr.Typeflag == tar.TypeSymlink {
+					entry += " -> " + hdr.Linkname
+				}
+
+				e.built = append(e.built, entry)
+			}
+
+			_, _ = w.Write([]byte(`{"stream":"done\n"}`))
+		},
+	)
+	mux.HandleFunc(
+		"DELETE /v1.41/images/{name}",
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[]`))
+		},
+	)
+	mux.HandleFunc(
+		"POST /v1.41/containers/{id}/exec",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id":"exec1"}`))
+		},
+	)
+	mux.HandleFunc(
+		"POST /v1.41/exec/{id}/start",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") == "tcp" {
+				// echo stdin like `cat` on the hijacked connection
+				_, _ = io.Copy(io.Discard, r.Body)
+
+				conn, rw, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				_, _ = rw.WriteString(
+					"HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n",
+				)
+				_ = rw.Flush()
+
+				in, _ := io.ReadAll(rw)
+
+				writeFrame(conn, 1, string(in))
+
+				return
+			}
+
+			writeFrame(w, 1, "ok")
+			writeFrame(w, 2, "noise")
+		},
+	)
+	mux.HandleFunc(
+		"GET /v1.41/exec/{id}/json",
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"ExitCode":3,"Running":false}`))
+		},
+	)
+
+	return mux
+}
+
+func TestDockerAPIRuntime(tt *testing.T) {
+	// a reaper would outlive the test and talk to the closed socket
+	tt.Setenv("DFT_REAPER", "0")
+
+	// unix socket paths are limited in length, t.TempDir may be too long
+	dir, err := os.MkdirTemp("", "dft")
+	if err != nil {
+		tt.Fatalf("[os.MkdirTemp] unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "docker.sock")
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		tt.Fatalf("[net.Listen] unexpected error: %v", err)
+	}
+
+	engine := &fakeEngine{}
+
+	srv := &http.Server{Handler: engine.handler()}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	rt, err := dft.NewDockerAPIRuntime("unix://" + socket)
+	if err != nil {
+		tt.Fatalf("[dft.NewDockerAPIRuntime] unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var c *dft.Container
+
+	// pulled reports if the engine pulled the image
+	pulled := func() bool {
+		engine.mu.Lock()
+		defer engine.mu.Unlock()
+
+		return engine.pulled
+	}
+
+	tt.Run(
+		"it does not pull if other resources are missing",
+		func(t *testing.T) {
+			_, err := rt.Create(ctx, dft.RunConfig{Image: "mongo:7-jammy", Network: "missing"})
+			if err == nil || !strings.Contains(err.Error(), "network missing not found") {
+				t.Errorf("[rt.Create] unexpected error: %v", err)
+			}
+
+			if pulled() {
+				t.Error("[rt.Create] unexpected pull")
+			}
+		},
+	)
+
+	tt.Run(
+		"it does not pull if pulling is disabled",
+		func(t *testing.T) {
+			_, err := rt.Create(ctx, dft.RunConfig{Image: "mongo:7-jammy", NoPull: true})
+			if !errors.Is(err, dft.ErrImageNotPresent) {
+				t.Errorf("[rt.Create] unexpected error: %v", err)
+			}
+
+			if pulled() {
+				t.Error("[rt.Create] unexpected pull")
+			}
+		},
+	)
+
+	tt.Run(
+		"it can start a container and pull a missing image",
+		func(t *testing.T) {
+			c, err = dft.StartContainer(
+				ctx,
+				"mongo:7-jammy",
+				dft.WithRandomPort(27017),
+				dft.WithRuntime(rt),
+			)
+			if err != nil {
+				t.Errorf("[dft.StartContainer] unexpected error: %v", err)
+				tt.FailNow()
+
+				return
+			}
+		},
+	)
+
+	tt.Run(
+		"it can retrieve the published ports",
+		func(t *testing.T) {
+			addrs, ok := c.ExposedPortAddresses(27017)
+			if !ok || len(addrs) != 2 {
+				t.Errorf("[ctr.ExposedPortAddresses] unexpected addresses: %v", addrs)
+
+				return
+			}
+
+			if addrs[0] != "0.0.0.0:32768" || addrs[1] != "[::]:32768" {
+				t.Errorf("[ctr.ExposedPortAddresses] unexpected addresses: %v", addrs)
+			}
+
+			if _, ok := c.ExposedPorts(9999); ok {
+				t.Error("[ctr.ExposedPorts] returned an unpublished port")
+			}
+		},
+	)
+
+	tt.Run(
+		"it can demultiplex logs",
+		func(t *testing.T) {
+			logs, err := c.Logs(ctx)
+			if err != nil {
+				t.Errorf("[ctr.Logs] unexpected error: %v", err)
+
+				return
+			}
+
+			if logs != "waiting for connections\nwarning\n" {
+				t.Errorf("[ctr.Logs] unexpected logs: %q", logs)
+			}
+		},
+	)
+
+	tt.Run(
+		"it can execute a command inside of the container",
+		func(t *testing.T) {
+			err := c.WaitCmd(
+				ctx,
+				[]string{"true"},
+				func(stdOut string, stdErr string, code int) bool {
+					return stdOut == "ok" && stdErr == "noise" && code == 3
+				},
+				dft.WithExecuteInsideContainer(true),
+			)
+			if err != nil {
+				t.Errorf("[ctr.WaitCmd] unexpected error: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it streams stdin into an executed command",
+		func(t *testing.T) {
+			res, err := c.Exec(
+				ctx,
+				[]string{"cat"},
+				dft.ExecOptions{Stdin: strings.NewReader("SELECT 1;")},
+			)
+
+			var exitErr *dft.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode != 3 {
+				t.Errorf("[ctr.Exec] expected exit error with code 3, got: %v", err)
+			}
+
+			if res.Stdout != "SELECT 1;" {
+				t.Errorf("[ctr.Exec] unexpected stdOut: %q", res.Stdout)
+			}
+		},
+	)
+
+	tt.Run(
+		"it archives symlinks of the build context as links",
+		func(t *testing.T) {
+			dir := t.TempDir()
+
+			err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\n"), 0o644)
+			if err != nil {
+				t.Fatalf("[os.WriteFile] unexpected error: %v", err)
+			}
+
+			err = os.Mkdir(filepath.Join(dir, "bin"), 0o755)
+			if err != nil {
+				t.Fatalf("[os.Mkdir] unexpected error: %v", err)
+			}
+
+			err = os.Symlink("../Dockerfile", filepath.Join(dir, "bin", "tool"))
+			if err != nil {
+				t.Fatalf("[os.Symlink] unexpected error: %v", err)
+			}
+
+			_, err = dft.BuildImage(ctx, os.DirFS(dir), dft.WithBuildRuntime(rt))
+			if err != nil {
+				t.Errorf("[dft.BuildImage] unexpected error: %v", err)
+
+				return
+			}
+
+			// the tracked image belongs to this engine only
+			if err = dft.RemoveBuiltImages(ctx); err != nil {
+				t.Errorf("[dft.RemoveBuiltImages] unexpected error: %v", err)
+			}
+
+			engine.mu.Lock()
+			defer engine.mu.Unlock()
+
+			want := []string{"Dockerfile", "bin/", "bin/tool -> ../Dockerfile"}
+			if strings.Join(engine.built, ",") != strings.Join(want, ",") {
+				t.Errorf("[dft.BuildImage] unexpected context: %q", engine.built)
+			}
+		},
+	)
+
+	tt.Run(
+		"it can stop a container",
+		func(t *testing.T) {
+			if err := c.Stop(ctx); err != nil {
+				t.Errorf("[ctr.Stop] unexpected error: %v", err)
+
+				return
+			}
+
+			if _, err := c.Logs(ctx); err == nil {
+				t.Error("[ctr.Logs] expected error from removed container")
+			}
+
+			if _, err := rt.State(ctx, "0123456789ab"); !errors.Is(err, dft.ErrContainerNotFound) {
+				t.Errorf("[rt.State] expected not found error, got: %v", err)
+			}
+		},
+	)
+}
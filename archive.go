@@ -0,0 +1,300 @@
+package dft
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// readLinkFS is implemented by file systems that can read symlinks,
+// e.g. `os.DirFS` since Go 1.25
+type readLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// hostFS is a directory on the host that can read symlinks
+// with any Go version
+type hostFS struct {
+	fs.FS
+	root string
+}
+
+// dirFS returns the directory on the host as file system
+func dirFS(root string) hostFS {
+	return hostFS{FS: os.DirFS(root), root: root}
+}
+
+// ReadLink returns the target of the symlink
+func (h hostFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return os.Readlink(filepath.Join(h.root, filepath.FromSlash(name)))
+}
+
+// readLink returns the target of the symlink inside of fsys
+func readLink(fsys fs.FS, name string) (string, error) {
+	lfs, ok := fsys.(readLinkFS)
+	if !ok {
+		return "", fmt.Errorf(
+			"unable to read symlink %s: not supported by the file system",
+			name,
+		)
+	}
+
+	return lfs.ReadLink(name)
+}
+
+// writeTar writes the content of fsys as a tar archive into w,
+// all entries are placed below prefix (if not empty).
+// A mode other than 0 replaces the permissions of the regular files.
+func writeTar(w io.Writer, fsys fs.FS, prefix string, mode fs.FileMode) error {
+	tw := tar.NewWriter(w)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		entry := path.Join(prefix, name)
+		if entry == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// INFO: symlinks are archived as such, e.g. node_modules/.bin
+		var link string
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err = readLink(fsys, name)
+			if err != nil {
+				return err
+			}
+		case !d.IsDir() && !info.Mode().IsRegular():
+			return fmt.Errorf("unable to archive %s: not a regular file", name)
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = entry
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if hdr.Typeflag == tar.TypeReg && mode != 0 {
+			hdr.Mode = int64(mode.Perm())
+		}
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// writeHostTar writes the file or directory tree on the host as a tar
+// archive into w, named as given.
+// A mode other than 0 replaces the permissions of the regular files.
+func writeHostTar(
+	w io.Writer,
+	hostPath string,
+	name string,
+	mode fs.FileMode,
+) error {
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return writeTar(w, dirFS(hostPath), name, mode)
+	}
+
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	hdr.Name = name
+	if mode != 0 {
+		hdr.Mode = int64(mode.Perm())
+	}
+
+	tw := tar.NewWriter(w)
+
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(tw, f); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// fileTar returns a tar archive holding a single file
+func fileTar(name string, data []byte, mode fs.FileMode) (io.Reader, error) {
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     int64(len(data)),
+		Mode:     int64(mode.Perm()),
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return &buf, nil
+}
+
+// extractTar extracts the tar archive into the directory on the host
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// INFO: archives must not write outside of dir
+		if !filepath.IsLocal(filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/"))) {
+			return fmt.Errorf("unable to extract %s: invalid path", hdr.Name)
+		}
+
+		trgt := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		mode := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(trgt, mode|0o700)
+		case tar.TypeReg:
+			err = writeFile(trgt, tr, mode)
+		default:
+			err = fmt.Errorf("unable to extract %s: not a regular file", hdr.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// copyToDir copies the content of fsys into the directory on the host
+func copyToDir(dir string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		trgt := filepath.Join(dir, filepath.FromSlash(name))
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return os.MkdirAll(trgt, info.Mode().Perm()|0o700)
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, err := readLink(fsys, name)
+			if err != nil {
+				return err
+			}
+
+			if err = os.MkdirAll(filepath.Dir(trgt), 0o700); err != nil {
+				return err
+			}
+
+			return os.Symlink(link, trgt)
+		}
+
+		src, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		return writeFile(trgt, src, info.Mode().Perm())
+	})
+}
+
+func writeFile(name string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(dst, r); err != nil {
+		_ = dst.Close()
+
+		return err
+	}
+
+	return dst.Close()
+}
@@ -0,0 +1,215 @@
+package dft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+const defaultDockerfile = "Dockerfile"
+
+type buildCfg struct {
+	dockerfile *string
+	args       map[string]string
+	target     *string
+	labels     map[string]string
+	tag        *string
+	output     io.Writer
+	runtime    Runtime
+}
+
+// BuildOption configures `BuildImage`
+type BuildOption func(cfg *buildCfg)
+
+// WithDockerfile sets the path of the Dockerfile inside of the build context
+// (default: "Dockerfile")
+func WithDockerfile(path string) BuildOption {
+	return func(cfg *buildCfg) {
+		cfg.dockerfile = &path
+	}
+}
+
+// WithBuildArg sets a build argument (--build-arg).
+// Can be called multiple times.
+func WithBuildArg(key string, value string) BuildOption {
+	return func(cfg *buildCfg) {
+		if cfg.args == nil {
+			cfg.args = map[string]string{}
+		}
+
+		cfg.args[key] = value
+	}
+}
+
+// WithTarget builds the given stage of a multi-stage Dockerfile
+func WithTarget(stage string) BuildOption {
+	return func(cfg *buildCfg) {
+		cfg.target = &stage
+	}
+}
+
+// WithBuildLabel adds a label to the image.
+// Can be called multiple times.
+func WithBuildLabel(key string, value string) BuildOption {
+	return func(cfg *buildCfg) {
+		if cfg.labels == nil {
+			cfg.labels = map[string]string{}
+		}
+
+		cfg.labels[key] = value
+	}
+}
+
+// WithTag sets the reference of the image instead of a generated unique one
+func WithTag(tag string) BuildOption {
+	return func(cfg *buildCfg) {
+		cfg.tag = &tag
+	}
+}
+
+// WithBuildOutput streams the build output into w
+func WithBuildOutput(w io.Writer) BuildOption {
+	return func(cfg *buildCfg) {
+		cfg.output = w
+	}
+}
+
+// WithBuildRuntime sets the container engine used to build the image
+// (default: `DetectRuntime()`)
+func WithBuildRuntime(rt Runtime) BuildOption {
+	return func(cfg *buildCfg) {
+		cfg.runtime = rt
+	}
+}
+
+// builtImage is an image built by `BuildImage` that needs to be removed
+type builtImage struct {
+	ref string
+	rt  Runtime
+}
+
+var (
+	builtImagesMu sync.Mutex
+	builtImages   []builtImage
+)
+
+// BuildImage builds an image from the build context and returns its
+// reference, which can be passed to `StartContainer`.
+// Use `os.DirFS(dir)` to build from a directory or an `embed.FS` to build
+// from an in-memory context. Symlinks are kept as links if the file system
+// can read them (`os.DirFS` since Go 1.25).
+//
+// Built images are tracked and can be removed with `RemoveBuiltImages`,
+// the session reaper removes them once the test process is gone.
+// On failure the error contains the build output.
+func BuildImage(
+	ctx context.Context,
+	buildContext fs.FS,
+	opts ...BuildOption,
+) (string, error) {
+	cfg := buildCfg{
+		dockerfile: nil,
+		args:       nil,
+		target:     nil,
+		labels:     nil,
+		tag:        nil,
+		output:     nil,
+		runtime:    nil,
+	}
+
+	for i := range opts {
+		opts[i](&cfg)
+	}
+
+	rt := cfg.runtime
+	if rt == nil {
+		var err error
+
+		rt, err = defaultRuntime()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	watchSession(rt)
+
+	labels := make(map[string]string, len(cfg.labels)+1)
+
+	for k, v := range cfg.labels {
+		labels[k] = v
+	}
+
+	// the reaper removes the image once this process is gone
+	labels[labelBuildSession] = session
+
+	bCfg := BuildConfig{
+		Context:    buildContext,
+		Dockerfile: defaultDockerfile,
+		Args:       cfg.args,
+		Labels:     labels,
+		Tag:        "dft-build:" + randomSuffix(),
+	}
+
+	if cfg.dockerfile != nil {
+		bCfg.Dockerfile = *cfg.dockerfile
+	}
+
+	if cfg.target != nil {
+		bCfg.Target = *cfg.target
+	}
+
+	if cfg.tag != nil {
+		bCfg.Tag = *cfg.tag
+	}
+
+	var out bytes.Buffer
+
+	w := io.Writer(&out)
+	if cfg.output != nil {
+		w = io.MultiWriter(&out, cfg.output)
+	}
+
+	err := rt.Build(ctx, bCfg, w)
+	if err != nil {
+		return "", fmt.Errorf(
+			"[%s] %w\noutput:\n%s",
+			bCfg.Tag,
+			err,
+			out.String(),
+		)
+	}
+
+	builtImagesMu.Lock()
+	builtImages = append(builtImages, builtImage{ref: bCfg.Tag, rt: rt})
+	builtImagesMu.Unlock()
+
+	return bCfg.Tag, nil
+}
+
+// RemoveBuiltImages removes all images built by `BuildImage`,
+// e.g. at the end of `TestMain`
+func RemoveBuiltImages(ctx context.Context) error {
+	builtImagesMu.Lock()
+	defer builtImagesMu.Unlock()
+
+	var (
+		errs   []error
+		remain []builtImage
+	)
+
+	for i := range builtImages {
+		err := builtImages[i].rt.RemoveImage(ctx, builtImages[i].ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[%s] %w", builtImages[i].ref, err))
+			remain = append(remain, builtImages[i])
+		}
+	}
+
+	builtImages = remain
+
+	return errors.Join(errs...)
+}
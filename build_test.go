@@ -0,0 +1,153 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestBuildImage(tt *testing.T) {
+	buildContext := fstest.MapFS{
+		"build/Dockerfile": &fstest.MapFile{Data: []byte("FROM alpine\nCOPY . /app\n")},
+		"main.go":          &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	tt.Run(
+		"it builds and removes tagged images",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got dft.BuildConfig
+
+			rt := dfttest.NewRuntime(dfttest.WithBuild(
+				func(cfg dft.BuildConfig, _ io.Writer) error {
+					got = cfg
+
+					_, err := fs.Stat(cfg.Context, cfg.Dockerfile)
+
+					return err
+				},
+			))
+
+			ref, err := dft.BuildImage(
+				ctx,
+				buildContext,
+				dft.WithDockerfile("build/Dockerfile"),
+				dft.WithBuildArg("VERSION", "dev"),
+				dft.WithTarget("test"),
+				dft.WithBuildRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.BuildImage] unexpected error: %v", err)
+			}
+
+			if !strings.HasPrefix(ref, "dft-build:") || got.Tag != ref {
+				t.Errorf("[dft.BuildImage] unexpected reference: %s", ref)
+			}
+
+			if got.Args["VERSION"] != "dev" || got.Target != "test" {
+				t.Errorf("[dft.BuildImage] unexpected config: %+v", got)
+			}
+
+			// the reaper removes images of crashed test runs
+			if got.Labels["dft.build.session"] != dft.SessionID() {
+				t.Errorf("[dft.BuildImage] unexpected labels: %v", got.Labels)
+			}
+
+			if err = dft.RemoveBuiltImages(ctx); err != nil {
+				t.Fatalf("[dft.RemoveBuiltImages] unexpected error: %v", err)
+			}
+
+			if len(rt.Images()) != 0 {
+				t.Errorf("[dft.RemoveBuiltImages] images were not removed: %v", rt.Images())
+			}
+		},
+	)
+
+	tt.Run(
+		"it includes the build output in errors",
+		func(t *testing.T) {
+			rt := dfttest.NewRuntime(dfttest.WithBuild(
+				func(_ dft.BuildConfig, w io.Writer) error {
+					fmt.Fprintln(w, "Step 2/2 : RUN go build")
+					fmt.Fprintln(w, "main.go:3:1: syntax error")
+
+					return errors.New("exit status 1")
+				},
+			))
+
+			_, err := dft.BuildImage(
+				context.Background(),
+				buildContext,
+				dft.WithBuildRuntime(rt),
+			)
+			if err == nil || !strings.Contains(err.Error(), "syntax error") {
+				t.Errorf("[dft.BuildImage] unexpected error: %v", err)
+			}
+
+			if len(rt.Images()) != 0 {
+				t.Errorf("[dft.BuildImage] failed build was tracked: %v", rt.Images())
+			}
+		},
+	)
+
+	tt.Run(
+		"it keeps symlinks of the build context for the engine",
+		func(t *testing.T) {
+			dir := stubEngine(t, "docker", `
+if [ "$1" = "build" ]; then
+	for ctx; do :; done
+	if [ -L "$ctx/bin/tool" ]; then echo link > "$dir/build"; fi
+fi
+`)
+
+			src := t.TempDir()
+
+			err := os.WriteFile(filepath.Join(src, "Dockerfile"), []byte("FROM scratch\n"), 0o644)
+			if err != nil {
+				t.Fatalf("[os.WriteFile] unexpected error: %v", err)
+			}
+
+			err = os.Mkdir(filepath.Join(src, "bin"), 0o755)
+			if err != nil {
+				t.Fatalf("[os.Mkdir] unexpected error: %v", err)
+			}
+
+			err = os.Symlink("../Dockerfile", filepath.Join(src, "bin", "tool"))
+			if err != nil {
+				t.Fatalf("[os.Symlink] unexpected error: %v", err)
+			}
+
+			_, err = dft.BuildImage(
+				context.Background(),
+				os.DirFS(src),
+				dft.WithBuildRuntime(dft.NewDockerRuntime()),
+			)
+			if err != nil {
+				t.Fatalf("[dft.BuildImage] unexpected error: %v", err)
+			}
+
+			// the tracked image belongs to the stub only
+			if err = dft.RemoveBuiltImages(context.Background()); err != nil {
+				t.Errorf("[dft.RemoveBuiltImages] unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, "build"))
+			if err != nil || string(got) != "link\n" {
+				t.Errorf("[dft.BuildImage] symlink was not kept: %q, %v", got, err)
+			}
+		},
+	)
+}
@@ -3,17 +3,47 @@ package dft
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-const intervalWait = 150
+const (
+	intervalAlive = 200
+	intervalWait  = 150
+
+	stateCreated    = "created"
+	stateDead       = "dead"
+	stateExited     = "exited"
+	statePaused     = "paused"
+	stateRestarting = "restarting"
+	stateRunning    = "running"
+
+	healthHealthy   = "healthy"
+	healthUnhealthy = "unhealthy"
+)
 
 type Container struct {
-	id           string
-	portMappings map[uint][]string
+	id string
+	rt Runtime
+	// ports are refreshed by `Restart`
+	ports    *publishedPorts
+	networks []*Network
+	// aliases maps the names of the joined networks onto the aliases
+	// of the container, to reconnect it after a partition
+	aliases  map[string][]string
+	labels   map[string]string
+	follower *logFollower
+	restarts *restarts
+	procs    *processes
+	proxies  *proxies
+	// reused containers are kept running on `Stop`
+	reused bool
+	// shared is set for containers used by multiple processes
+	shared *sharedRef
 }
 
 func newContainer(
@@ -22,10 +52,22 @@ func newContainer(
 	opts ...ContainerOption,
 ) (*Container, error) {
 	cfg := containerCfg{
-		args:   nil,
-		env:    nil,
-		mounts: nil,
-		ports:  nil,
+		args:          nil,
+		env:           nil,
+		mounts:        nil,
+		ports:         nil,
+		runtime:       nil,
+		waitFor:       nil,
+		healthCheck:   nil,
+		noHealthCheck: nil,
+		networks:      nil,
+		pullPolicy:    nil,
+		labels:        nil,
+		logConsumers:  nil,
+		files:         nil,
+		capabilities:  nil,
+		reuse:         nil,
+		noSession:     nil,
 	}
 
 	// INFO: we could pass the options further down and parse them in functions
@@ -39,6 +81,10 @@ func newContainer(
 		envVars      []string
 		exposedPorts [][2]uint
 		mounts       [][2]string
+		networks     []networkAttachment
+		labels       map[string]string
+		files        []fileInjection
+		capabilities []string
 	)
 
 	if cfg.args != nil {
@@ -57,24 +103,147 @@ func newContainer(
 		exposedPorts = *cfg.ports
 	}
 
-	id, err := startContainer(
-		ctx,
-		imageName,
-		arguments,
-		envVars,
-		exposedPorts,
-		mounts,
+	if cfg.networks != nil {
+		networks = *cfg.networks
+	}
+
+	if cfg.labels != nil {
+		labels = *cfg.labels
+	}
+
+	if cfg.files != nil {
+		files = *cfg.files
+	}
+
+	if cfg.capabilities != nil {
+		capabilities = *cfg.capabilities
+	}
+
+	rt := cfg.runtime
+	if rt == nil {
+		var err error
+
+		rt, err = defaultRuntime()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	watchSession(rt)
+
+	if cfg.pullPolicy != nil {
+		err := ensureImage(ctx, rt, imageName, *cfg.pullPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", imageName, err)
+		}
+	}
+
+	runCfg := RunConfig{
+		Image:         imageName,
+		Cmd:           arguments,
+		Env:           envVars,
+		Ports:         exposedPorts,
+		Mounts:        mounts,
+		HealthCheck:   cfg.healthCheck,
+		NoHealthCheck: cfg.noHealthCheck != nil && *cfg.noHealthCheck,
+		Labels:        containerLabels(imageName, labels),
+		CapAdd:        capabilities,
+		NoPull:        cfg.pullPolicy != nil && *cfg.pullPolicy == PullNever,
+	}
+
+	// INFO: engines only support a single network on run,
+	// additional ones are connected once the container is up
+	if len(networks) > 0 {
+		runCfg.Network = networks[0].network.name
+		runCfg.NetworkAliases = networks[0].aliases
+	}
+
+	reuse := cfg.reuse != nil && *cfg.reuse && reuseEnabled()
+
+	// reused and shared containers outlive the test process,
+	// the session reaper must not remove them
+	if reuse || (cfg.noSession != nil && *cfg.noSession) {
+		delete(runCfg.Labels, labelSession)
+	}
+
+	var (
+		id      string
+		hash    string
+		err     error
+		adopted bool
 	)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"[%s](%s) %w",
-			imageName,
-			id,
-			err,
-		)
+
+	if reuse {
+		// INFO: networks get unique names per run, a container attached
+		// to the network of a previous run can not be reused
+		if len(networks) > 0 {
+			return nil, fmt.Errorf(
+				"[%s] WithReuse can not be combined with WithNetwork",
+				imageName,
+			)
+		}
+
+		hash, err = reuseHash(runCfg, labels, files)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", imageName, err)
+		}
+
+		runCfg.Name = reusePrefix + hash
+		runCfg.Labels[labelReuse] = hash
+
+		id, err = findReusable(ctx, rt, hash)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", imageName, err)
+		}
+
+		adopted = id != ""
 	}
 
-	// at this point we have a container up
+	if !adopted {
+		id, err = rt.Create(ctx, runCfg)
+		if reuse && errors.Is(err, ErrNameConflict) {
+			// INFO: another process created the container after we looked
+			// for it, e.g. a parallel package of `go test ./...`
+			id, err = awaitReusable(ctx, rt, hash)
+			adopted = err == nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"[%s](%s) %w",
+				imageName,
+				id,
+				err,
+			)
+		}
+	}
+
+	ctrLabels := runCfg.Labels
+
+	if adopted {
+		// INFO: e.g. dft.started of the adopted container differs from ours
+		ctrLabels, err = inspectLabels(ctx, rt, id)
+		if err != nil {
+			return nil, fmt.Errorf("[%s](%s) %w", imageName, id, err)
+		}
+	}
+
+	c := &Container{
+		id:       id,
+		rt:       rt,
+		labels:   ctrLabels,
+		ports:    &publishedPorts{},
+		restarts: &restarts{},
+		procs:    &processes{},
+		proxies:  &proxies{},
+		reused:   reuse,
+	}
+
+	if len(networks) > 0 {
+		c.joined(networks[0].network, networks[0].aliases)
+	}
+
+	// at this point we have a container
 	// but it may not be able to meet our conditions
 	// in the given context.
 	// In this case we will throw an error and not returning
@@ -82,20 +251,59 @@ func newContainer(
 	// of its removal
 	defer func() {
 		if err != nil {
-			ctr := Container{id: id}
-
 			sCtx, sCtxCancel := context.WithTimeout(
 				context.Background(),
 				5*time.Second,
 			)
-			_ = ctr.Stop(sCtx)
+			// a container that failed to start must not be reused
+			c.reused = false
+			_ = c.Stop(sCtx)
 			sCtxCancel()
 		}
 	}()
 
-	err = containerIsAlive(ctx, id)
+	// an adopted container is running already, with its files in place
+	if !adopted {
+		// files have to be in place before the entrypoint runs
+		for i := range files {
+			if files[i].fsys != nil {
+				err = c.copyFS(ctx, files[i].fsys, files[i].path)
+			} else {
+				err = c.WriteFile(ctx, files[i].path, files[i].content, files[i].mode)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf(
+					"[%s](%s) unable to inject %s: %w",
+					imageName,
+					id,
+					files[i].path,
+					err,
+				)
+			}
+		}
+
+		err = rt.Start(ctx, id)
+		if err != nil {
+			l := logTail(rt, id)
+
+			return nil, fmt.Errorf(
+				"[%s](%s) %w\nlogs:%s",
+				imageName,
+				id,
+				err,
+				l,
+			)
+		}
+	}
+
+	if cfg.logConsumers != nil {
+		c.followLogs(*cfg.logConsumers)
+	}
+
+	err = waitForState(ctx, rt, id, stateRunning)
 	if err != nil {
-		l, _ := getLogs(ctx, id)
+		l := logTail(rt, id)
 
 		return nil, fmt.Errorf(
 			"[%s](%s) %w\nlogs:%s",
@@ -106,43 +314,24 @@ func newContainer(
 		)
 	}
 
+	for i := 1; i < len(networks); i++ {
+		err = c.Connect(ctx, networks[i].network, networks[i].aliases...)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"[%s](%s) %w",
+				imageName,
+				id,
+				err,
+			)
+		}
+	}
+
 	prtMpns := map[uint][]string{}
 
 	if len(exposedPorts) > 0 {
-		errCh := make(chan error)
-
-		go func() {
-			t := time.NewTicker(intervalWait * time.Millisecond)
-			defer t.Stop()
-
-			for {
-				select {
-				case <-ctx.Done():
-					errCh <- ctx.Err()
-
-					return
-				case <-t.C:
-					pm, pErr := getPublishedPorts(ctx, id)
-					if pErr != nil {
-						errCh <- pErr
-
-						return
-					}
-
-					if len(pm) == 0 {
-						continue
-					}
-
-					prtMpns = pm
-					errCh <- nil
-
-					return
-				}
-			}
-		}()
-
-		if err = <-errCh; err != nil {
-			l, _ := getLogs(ctx, id)
+		prtMpns, err = waitForPorts(ctx, rt, id)
+		if err != nil {
+			l := logTail(rt, id)
 
 			return nil, fmt.Errorf(
 				"[%s](%s) %w\nlogs:%s",
@@ -166,46 +355,212 @@ func newContainer(
 	// 	)
 	// }
 
-	return &Container{
-		id:           id,
-		portMappings: prtMpns,
-	}, nil
+	c.ports.set(prtMpns)
+
+	if cfg.waitFor != nil {
+		if err = c.Wait(ctx, *cfg.waitFor...); err != nil {
+			l := logTail(rt, id)
+
+			return nil, fmt.Errorf(
+				"[%s](%s) %w\nlogs:%s",
+				imageName,
+				id,
+				err,
+				l,
+			)
+		}
+	}
+
+	return c, nil
+}
+
+// waitForState polls the state of the container until it reached the wanted
+// one, failing on states the container does not leave by itself
+func waitForState(
+	ctx context.Context,
+	rt Runtime,
+	id string,
+	want string,
+) error {
+	t := time.NewTicker(intervalAlive * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			state, err := rt.State(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if state == want {
+				return nil
+			}
+
+			switch state {
+			case stateDead,
+				stateExited,
+				statePaused,
+				stateRestarting:
+				return fmt.Errorf(
+					"container in invalid state: '%s'",
+					state,
+				)
+			}
+		}
+	}
+}
+
+// waitForPorts polls the published ports until the engine reports them
+func waitForPorts(
+	ctx context.Context,
+	rt Runtime,
+	id string,
+) (map[uint][]string, error) {
+	t := time.NewTicker(intervalWait * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+			pm, err := rt.Ports(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(pm) == 0 {
+				continue
+			}
+
+			return pm, nil
+		}
+	}
 }
 
 // Stop will stop the container and remove it (as well as related volumes)
-// from the host system
+// from the host system.
+// Networks dft created on its own are removed with their last container.
+// Containers started `WithReuse` keep running for the next test run,
+// containers from `Shared` until the last process stopped them.
 func (c Container) Stop(ctx context.Context) error {
-	err := stopContainer(ctx, c.id)
+	if c.reused {
+		c.detach(ctx)
+
+		return nil
+	}
+
+	if c.shared != nil {
+		return c.shared.release(ctx, c)
+	}
+
+	return c.stop(ctx)
+}
+
+// stop stops and removes the container
+func (c Container) stop(ctx context.Context) error {
+	err := c.rt.Stop(ctx, c.id)
+
+	if c.procs != nil {
+		c.procs.stop()
+	}
+
+	if c.proxies != nil {
+		c.proxies.close()
+	}
+
+	if c.follower != nil {
+		// consumers must not be called once Stop returned,
+		// e.g. `t.Log` panics after the test finished
+		c.follower.stop(ctx)
+	}
+
 	if err != nil {
 		return err
 	}
 
-	ids, err := getVolumes(ctx, c.id)
+	err = removeWithVolumes(ctx, c.rt, c.id)
 	if err != nil {
 		return err
 	}
 
-	err = removeContainer(ctx, c.id)
+	for i := range c.networks {
+		err = c.networks[i].release(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeWithVolumes removes the stopped container and its volumes
+func removeWithVolumes(ctx context.Context, rt Runtime, id string) error {
+	ids, err := rt.Volumes(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	if len(ids) == 0 {
-		return nil
+	err = rt.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		return rt.RemoveVolumes(ctx, ids)
+	}
+
+	return nil
+}
+
+// detach releases everything attached to the container,
+// which keeps running
+func (c Container) detach(ctx context.Context) {
+	if c.procs != nil {
+		c.procs.kill(ctx)
+		c.procs.stop()
+	}
+
+	if c.proxies != nil {
+		c.proxies.close()
+	}
+
+	if c.follower != nil {
+		// the logs of a running container never end
+		c.follower.cancel()
+		<-c.follower.done
 	}
+}
+
+// Labels returns the labels of the container, including the dft.* ones
+// (version, session, image and start time)
+func (c *Container) Labels() map[string]string {
+	l := make(map[string]string, len(c.labels))
+
+	for k, v := range c.labels {
+		l[k] = v
+	}
+
+	return l
+}
 
-	return deleteVolumes(ctx, ids)
+// Inspect returns the raw inspect output (JSON) of the container
+func (c *Container) Inspect(ctx context.Context) (string, error) {
+	return c.rt.Inspect(ctx, c.id)
 }
 
 // Logs will retrieve the latest logs from the container
 // This call errors once `Stop` was called.
 func (c *Container) Logs(ctx context.Context) (string, error) {
-	return getLogs(ctx, c.id)
+	return c.rt.Logs(ctx, c.id, LogOptions{})
 }
 
 // ExposedPorts will return a list of host ports exposing the internal port
 func (c *Container) ExposedPorts(port uint) ([]uint, bool) {
-	strs, ok := c.portMappings[port]
+	strs, ok := c.ports.get(port)
 	if !ok {
 		return nil, false
 	}
@@ -213,9 +568,12 @@ func (c *Container) ExposedPorts(port uint) ([]uint, bool) {
 	p := make([]uint, 0, len(strs))
 
 	for i := range strs {
-		parts := strings.Split(strs[i], ":")
-
-		v, err := strconv.ParseUint(parts[1], base10, bit64)
+		// IPv6 addresses contain colons as well, the port is always last
+		v, err := strconv.ParseUint(
+			strs[i][strings.LastIndex(strs[i], ":")+1:],
+			base10,
+			bit64,
+		)
 		if err != nil {
 			return nil, false
 		}
@@ -229,9 +587,44 @@ func (c *Container) ExposedPorts(port uint) ([]uint, bool) {
 // ExposedPortAddresses will return a list of host ports exposing the internal port in the format of
 // "<IP>:<PORT>"
 func (c *Container) ExposedPortAddresses(port uint) ([]string, bool) {
-	str, ok := c.portMappings[port]
+	return c.ports.get(port)
+}
 
-	return str, ok
+// publishedPorts maps the internal ports onto "<IP>:<PORT>" host addresses,
+// safe for concurrent use
+type publishedPorts struct {
+	mu sync.RWMutex
+	m  map[uint][]string
+}
+
+func (p *publishedPorts) get(port uint) ([]string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	addrs, ok := p.m[port]
+
+	return addrs, ok
+}
+
+// all returns a copy of the mappings
+func (p *publishedPorts) all() map[uint][]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	m := make(map[uint][]string, len(p.m))
+
+	for k, v := range p.m {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (p *publishedPorts) set(m map[uint][]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.m = m
 }
 
 // WaitCmd takes a command in the form of a ["<cmd>", "(<arg> | <-flag> | <flagvalue>)"...]
@@ -266,32 +659,38 @@ func (c *Container) WaitCmd(
 				return
 			case <-t.C:
 				var (
-					outB bytes.Buffer
-					errB bytes.Buffer
-					code int
-					err  error
+					stdOut string
+					stdErr string
+					code   int
+					err    error
 				)
 
 				if inContainer {
 					// call docker exec
-					outB, errB, code, err = dockerExecute(ctx, c.id, cmd)
+					var res ExecResult
+
+					res, err = c.Exec(ctx, cmd, ExecOptions{})
+					stdOut, stdErr, code = res.Stdout, res.Stderr, res.ExitCode
 				} else {
 					// call func on host
+					var outB, errB bytes.Buffer
+
 					outB, errB, code, err = hostExecute(ctx, cmd)
+					stdOut, stdErr = outB.String(), errB.String()
 				}
 
 				if err != nil && code == -1 {
 					errCh <- fmt.Errorf(
 						"wait command errored: %w\n\tstdErr:%s\n\tstdOut:%s",
 						err,
-						errB.String(),
-						outB.String(),
+						stdErr,
+						stdOut,
 					)
 
 					return
 				}
 
-				if !metCondition(outB.String(), errB.String(), code) {
+				if !metCondition(stdOut, stdErr, code) {
 					continue
 				}
 
@@ -0,0 +1,192 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestStartContainer(tt *testing.T) {
+	tt.Run(
+		"it returns a running container with its published ports",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithStates(dfttest.StateCreated, dfttest.StateRunning),
+				dfttest.WithPorts(map[uint][]string{27017: {"0.0.0.0:32768"}}),
+				dfttest.WithVolumes("anon"),
+			)
+
+			c, err := dft.StartContainer(
+				ctx,
+				"mongo:7-jammy",
+				dft.WithRandomPort(27017),
+				dft.WithEnvVar("intent", "test"),
+				dft.WithRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			prts, ok := c.ExposedPorts(27017)
+			if !ok || len(prts) != 1 || prts[0] != 32768 {
+				t.Errorf("[ctr.ExposedPorts] unexpected ports: %v", prts)
+			}
+
+			cfg, _ := rt.Config(rt.Containers()[0])
+			if len(cfg.Env) != 1 || cfg.Env[0] != "INTENT=test" {
+				t.Errorf("[dft.StartContainer] unexpected env: %v", cfg.Env)
+			}
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[ctr.Stop] container was not removed")
+			}
+
+			if rt.Count("RemoveVolumes") != 1 {
+				t.Error("[ctr.Stop] volumes were not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it fails and cleans up if the container dies",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithStates(dfttest.StateCreated, dfttest.StateExited),
+				dfttest.WithLogs("invalid flag --tlsMode"),
+			)
+
+			_, err := dft.StartContainer(ctx, "mongo:7-jammy", dft.WithRuntime(rt))
+			if err == nil ||
+				!strings.Contains(err.Error(), "container in invalid state: 'exited'") {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if !strings.Contains(err.Error(), "invalid flag --tlsMode") {
+				t.Errorf("[dft.StartContainer] error does not contain logs: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[dft.StartContainer] dead container was not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it fails and cleans up if no ports get published in time",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			_, err := dft.StartContainer(
+				ctx,
+				"mongo:7-jammy",
+				dft.WithRandomPort(27017),
+				dft.WithRuntime(rt),
+			)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if rt.Count("Ports") == 0 {
+				t.Error("[dft.StartContainer] ports were never requested")
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[dft.StartContainer] container was not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it fails if the container never runs before the context expires",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(dfttest.WithStates(dfttest.StateCreated))
+
+			_, err := dft.StartContainer(ctx, "mongo:7-jammy", dft.WithRuntime(rt))
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[dft.StartContainer] container was not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it returns the error of the runtime",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			rtErr := errors.New("no space left on device")
+			rt := dfttest.NewRuntime(dfttest.WithRunError(rtErr))
+
+			_, err := dft.StartContainer(ctx, "mongo:7-jammy", dft.WithRuntime(rt))
+			if !errors.Is(err, rtErr) {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it labels the container",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			c, err := dft.StartContainer(
+				ctx,
+				"mongo:7-jammy",
+				dft.WithLabel("test", t.Name()),
+				dft.WithLabels(map[string]string{"ci.job": "42", "dft.session": "mine"}),
+				dft.WithRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+			defer c.Stop(ctx)
+
+			l := c.Labels()
+			if l["test"] != t.Name() || l["ci.job"] != "42" {
+				t.Errorf("[ctr.Labels] user labels are missing: %v", l)
+			}
+
+			// the dft.* labels can not be overwritten
+			if l["dft.session"] != dft.SessionID() || l["dft.image"] != "mongo:7-jammy" {
+				t.Errorf("[ctr.Labels] unexpected dft labels: %v", l)
+			}
+
+			if _, err = time.Parse(time.RFC3339, l["dft.started"]); err != nil {
+				t.Errorf("[ctr.Labels] unexpected start time: %v", err)
+			}
+
+			cfg, _ := rt.Config(rt.Containers()[0])
+			if cfg.Labels["test"] != t.Name() {
+				t.Errorf("[dft.StartContainer] labels were not passed: %v", cfg.Labels)
+			}
+		},
+	)
+}
@@ -0,0 +1,139 @@
+package dft
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// CopyTo copies the file or directory tree on the host to the path inside
+// of the container.
+// A mode other than 0 replaces the permissions of the copied files,
+// otherwise (and for directories) the modes of the host are kept.
+// The parent directory of the path has to exist inside of the container.
+func (c *Container) CopyTo(
+	ctx context.Context,
+	hostPath string,
+	containerPath string,
+	mode fs.FileMode,
+) error {
+	dir, name := splitTarget(containerPath)
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeHostTar(pw, hostPath, name, mode))
+	}()
+	// unblocks the writer if the runtime stops reading early
+	defer pr.Close()
+
+	err := c.rt.CopyTo(ctx, c.id, dir, pr)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", hostPath, err)
+	}
+
+	return nil
+}
+
+// CopyReaderTo copies the content of r into the file inside of the container
+func (c *Container) CopyReaderTo(
+	ctx context.Context,
+	r io.Reader,
+	containerPath string,
+	mode fs.FileMode,
+) error {
+	// the size has to be known for the tar header
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteFile(ctx, containerPath, data, mode)
+}
+
+// WriteFile writes data into the file inside of the container
+func (c *Container) WriteFile(
+	ctx context.Context,
+	containerPath string,
+	data []byte,
+	mode fs.FileMode,
+) error {
+	archive, err := fileTar(path.Base(containerPath), data, mode)
+	if err != nil {
+		return err
+	}
+
+	return c.rt.CopyTo(ctx, c.id, path.Dir(containerPath), archive)
+}
+
+// copyFS copies the content of fsys into the directory inside of the container
+func (c *Container) copyFS(ctx context.Context, fsys fs.FS, dir string) error {
+	parent, name := splitTarget(dir)
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeTar(pw, fsys, name, 0))
+	}()
+	defer pr.Close()
+
+	return c.rt.CopyTo(ctx, c.id, parent, pr)
+}
+
+// splitTarget returns the directory the archive is extracted into and
+// the name of its entry for the path inside of the container.
+// The entries of an archive for the root are named without a prefix,
+// they must not start with a slash.
+func splitTarget(containerPath string) (dir string, name string) {
+	containerPath = path.Clean(containerPath)
+
+	if containerPath == "/" {
+		return "/", ""
+	}
+
+	return path.Dir(containerPath), path.Base(containerPath)
+}
+
+// CopyFrom returns the file or directory tree inside of the container as a
+// tar archive, entries are named relative to the parent of the path.
+// The context has to stay valid until the archive is read,
+// the caller has to close it.
+func (c *Container) CopyFrom(
+	ctx context.Context,
+	containerPath string,
+) (io.ReadCloser, error) {
+	return c.rt.CopyFrom(ctx, c.id, containerPath)
+}
+
+// ReadFile returns the content of the file inside of the container
+func (c *Container) ReadFile(
+	ctx context.Context,
+	containerPath string,
+) ([]byte, error) {
+	rc, err := c.CopyFrom(ctx, containerPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+
+	hdr, err := tr.Next()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to read %s: empty archive", containerPath)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", containerPath, err)
+	}
+
+	if hdr.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("unable to read %s: not a regular file", containerPath)
+	}
+
+	return io.ReadAll(tr)
+}
@@ -0,0 +1,164 @@
+package dft_test
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestCopy(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime()
+
+	c, err := dft.StartContainer(ctx, "nginx", dft.WithRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	tt.Run(
+		"it writes and reads files",
+		func(t *testing.T) {
+			err := c.CopyReaderTo(ctx, strings.NewReader("listen 80;"), "/etc/nginx/nginx.conf", 0o644)
+			if err != nil {
+				t.Fatalf("[ctr.CopyReaderTo] unexpected error: %v", err)
+			}
+
+			b, err := c.ReadFile(ctx, "/etc/nginx/nginx.conf")
+			if err != nil || string(b) != "listen 80;" {
+				t.Errorf("[ctr.ReadFile] unexpected content: %q, %v", b, err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it copies directory trees from the host",
+		func(t *testing.T) {
+			dir := t.TempDir()
+
+			_ = os.MkdirAll(filepath.Join(dir, "certs"), 0o755)
+			_ = os.WriteFile(filepath.Join(dir, "certs", "ca.crt"), []byte("CA"), 0o600)
+
+			if err := c.CopyTo(ctx, dir, "/run/tls", 0); err != nil {
+				t.Fatalf("[ctr.CopyTo] unexpected error: %v", err)
+			}
+
+			b, ok := rt.File(rt.Containers()[0], "/run/tls/certs/ca.crt")
+			if !ok || string(b) != "CA" {
+				t.Errorf("[ctr.CopyTo] unexpected content: %q", b)
+			}
+
+			rc, err := c.CopyFrom(ctx, "/run/tls")
+			if err != nil {
+				t.Fatalf("[ctr.CopyFrom] unexpected error: %v", err)
+			}
+			defer rc.Close()
+
+			var names []string
+
+			tr := tar.NewReader(rc)
+			for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+				names = append(names, hdr.Name)
+			}
+
+			if strings.Join(names, ",") != "tls/,tls/certs/,tls/certs/ca.crt" {
+				t.Errorf("[ctr.CopyFrom] unexpected entries: %v", names)
+			}
+
+			if _, err = c.ReadFile(ctx, "/run/tls"); err == nil {
+				t.Error("[ctr.ReadFile] expected error for a directory")
+			}
+		},
+	)
+
+	tt.Run(
+		"it replaces the modes of copied files",
+		func(t *testing.T) {
+			dir := t.TempDir()
+
+			_ = os.WriteFile(filepath.Join(dir, "server.key"), []byte("KEY"), 0o644)
+
+			err := c.CopyTo(ctx, filepath.Join(dir, "server.key"), "/run/server.key", 0o600)
+			if err != nil {
+				t.Fatalf("[ctr.CopyTo] unexpected error: %v", err)
+			}
+
+			rc, err := c.CopyFrom(ctx, "/run/server.key")
+			if err != nil {
+				t.Fatalf("[ctr.CopyFrom] unexpected error: %v", err)
+			}
+			defer rc.Close()
+
+			hdr, err := tar.NewReader(rc).Next()
+			if err != nil {
+				t.Fatalf("[ctr.CopyFrom] unexpected error: %v", err)
+			}
+
+			if hdr.FileInfo().Mode().Perm() != 0o600 {
+				t.Errorf("[ctr.CopyTo] unexpected mode: %v", hdr.FileInfo().Mode())
+			}
+		},
+	)
+}
+
+func TestInjectFiles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime()
+
+	c, err := dft.StartContainer(
+		ctx,
+		"postgres",
+		dft.WithFile("/etc/postgresql/postgresql.conf", []byte("fsync = off"), 0o644),
+		dft.WithFS(
+			fstest.MapFS{"01-schema.sql": &fstest.MapFile{Data: []byte("CREATE TABLE t();")}},
+			"/docker-entrypoint-initdb.d",
+		),
+		dft.WithFS(
+			fstest.MapFS{"etc/motd": &fstest.MapFile{Data: []byte("hello")}},
+			"/",
+		),
+		dft.WithRuntime(rt),
+	)
+	if err != nil {
+		t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	var methods []string
+
+	for _, call := range rt.Calls() {
+		if call.Method == "CopyTo" || call.Method == "Start" {
+			methods = append(methods, call.Method)
+		}
+	}
+
+	if strings.Join(methods, ",") != "CopyTo,CopyTo,CopyTo,Start" {
+		t.Errorf("[dft.StartContainer] files were not injected before the start: %v", methods)
+	}
+
+	id := rt.Containers()[0]
+
+	if b, _ := rt.File(id, "/etc/postgresql/postgresql.conf"); string(b) != "fsync = off" {
+		t.Errorf("[dft.WithFile] unexpected content: %q", b)
+	}
+
+	if b, _ := rt.File(id, "/docker-entrypoint-initdb.d/01-schema.sql"); string(b) != "CREATE TABLE t();" {
+		t.Errorf("[dft.WithFS] unexpected content: %q", b)
+	}
+
+	if b, _ := rt.File(id, "/etc/motd"); string(b) != "hello" {
+		t.Errorf("[dft.WithFS] unexpected content: %q", b)
+	}
+}
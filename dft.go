@@ -2,10 +2,16 @@ package dft
 
 import (
 	"context"
-	"os/exec"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
 )
 
-const dockerCmd = "docker"
+const (
+	dockerCmd  = "docker"
+	nerdctlCmd = "nerdctl"
+	podmanCmd  = "podman"
+)
 
 // StartContainer tries to spin up a container for the given image.
 // This may take a while if the given image is not present on the host
@@ -15,13 +21,36 @@ func StartContainer(
 	imageName string,
 	opts ...ContainerOption,
 ) (*Container, error) {
-	if _, err := exec.LookPath(dockerCmd); err != nil {
-		return nil, err
-	}
-
 	return newContainer(
 		ctx,
 		imageName,
 		opts...,
 	)
 }
+
+// defaultRuntime returns the runtime used if no `WithRuntime` option was passed
+func defaultRuntime() (Runtime, error) {
+	return DetectRuntime()
+}
+
+// randomSuffix returns a random hex string to create unique names
+func randomSuffix() string {
+	b := make([]byte, 6)
+
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// sortedKeys returns the keys of the map in a stable order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,1224 @@
+// Package dfttest provides an in-memory `dft.Runtime` for unit tests.
+//
+// It allows testing code that takes a `*dft.Container` (and dft itself)
+// without a container engine: every call is recorded, outputs are scripted
+// and containers move through states like created -> running -> exited.
+package dfttest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/abecodes/dft"
+)
+
+// states a container can be in, matching the ones docker reports
+const (
+	StateCreated = "created"
+	StateDead    = "dead"
+	StateExited  = "exited"
+	StatePaused  = "paused"
+	StateRunning = "running"
+)
+
+// Call is a recorded invocation of the runtime
+type Call struct {
+	// Method is the name of the `dft.Runtime` method that was called
+	Method string
+	// ID is the container the call targeted (empty for `Create`)
+	ID string
+	// Args holds additional arguments like the exec command
+	Args []string
+}
+
+// ExecFunc scripts the result of `Exec` calls
+type ExecFunc func(
+	id string,
+	cmd []string,
+) (stdOut string, stdErr string, exitCode int, err error)
+
+// BuildFunc scripts the result of `Build` calls, output is written into w
+type BuildFunc func(cfg dft.BuildConfig, w io.Writer) error
+
+type container struct {
+	cfg     dft.RunConfig
+	states  []string
+	health  []dft.Health
+	logs    []logChunk
+	started bool
+	stopped bool
+	// restarts ends the followers, like the engines do once the container
+	// stops for a restart
+	restarts int
+	// networks maps the networks the container is attached to onto its
+	// address inside of them
+	networks map[string]string
+	// files holds the files and directories copied into the container
+	// by their absolute path
+	files map[string]file
+	// changed is closed and replaced whenever logs are written or the
+	// container stops, to wake up followers
+	changed chan struct{}
+}
+
+// file is a file or directory inside of a container
+type file struct {
+	data []byte
+	mode fs.FileMode
+	dir  bool
+}
+
+// logChunk is a single write to the logs of a container
+type logChunk struct {
+	stderr bool
+	data   string
+	time   time.Time
+}
+
+// notify wakes up all followers, the caller must hold the lock
+func (c *container) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Runtime is a scripted, in-memory implementation of `dft.Runtime`.
+// It is safe for concurrent use.
+type Runtime struct {
+	mu         sync.Mutex
+	calls      []Call
+	containers map[string]*container
+	nextID     int
+	networks   map[string]bool
+	nextIP     int
+	images     map[string]bool
+
+	runErr  error
+	pullErr error
+	states  []string
+	health  []dft.Health
+	ports   map[uint][]string
+	logs    string
+	execFn  ExecFunc
+	buildFn BuildFunc
+	volumes []string
+}
+
+// Option configures the fake runtime
+type Option func(rt *Runtime)
+
+// NewRuntime returns a fake runtime.
+// Without options containers are running immediately, publish no ports,
+// have no logs and every exec succeeds with exit code 0.
+func NewRuntime(opts ...Option) *Runtime {
+	rt := &Runtime{
+		containers: map[string]*container{},
+		networks:   map[string]bool{},
+		images:     map[string]bool{},
+		states:     []string{StateRunning},
+		ports:      map[uint][]string{},
+		execFn: func(string, []string) (string, string, int, error) {
+			return "", "", 0, nil
+		},
+		buildFn: func(dft.BuildConfig, io.Writer) error {
+			return nil
+		},
+	}
+
+	for i := range opts {
+		opts[i](rt)
+	}
+
+	return rt
+}
+
+// WithRunError makes every `Create` call fail with err
+func WithRunError(err error) Option {
+	return func(rt *Runtime) {
+		rt.runErr = err
+	}
+}
+
+// WithPullError makes every `Pull` call fail with err
+func WithPullError(err error) Option {
+	return func(rt *Runtime) {
+		rt.pullErr = err
+	}
+}
+
+// WithImages marks the images as present, as if they were pulled before
+func WithImages(refs ...string) Option {
+	return func(rt *Runtime) {
+		for i := range refs {
+			rt.images[refs[i]] = true
+		}
+	}
+}
+
+// WithStates sets the sequence of states a new container reports.
+// Every `State` call advances one step, the last state sticks.
+func WithStates(states ...string) Option {
+	return func(rt *Runtime) {
+		rt.states = states
+	}
+}
+
+// WithHealth sets the sequence of HEALTHCHECK states a new container reports.
+// Every `Health` call advances one step, the last state sticks.
+// Without this option containers have no HEALTHCHECK.
+func WithHealth(health ...dft.Health) Option {
+	return func(rt *Runtime) {
+		rt.health = health
+	}
+}
+
+// WithPorts sets the published ports reported for every container
+func WithPorts(ports map[uint][]string) Option {
+	return func(rt *Runtime) {
+		rt.ports = ports
+	}
+}
+
+// WithLogs sets the initial log output of every container
+func WithLogs(logs string) Option {
+	return func(rt *Runtime) {
+		rt.logs = logs
+	}
+}
+
+// WithExec scripts the result of `Exec` calls
+func WithExec(fn ExecFunc) Option {
+	return func(rt *Runtime) {
+		rt.execFn = fn
+	}
+}
+
+// WithBuild scripts the result of `Build` calls
+func WithBuild(fn BuildFunc) Option {
+	return func(rt *Runtime) {
+		rt.buildFn = fn
+	}
+}
+
+// WithVolumes sets the volumes reported as mounted into every container
+func WithVolumes(names ...string) Option {
+	return func(rt *Runtime) {
+		rt.volumes = names
+	}
+}
+
+// Calls returns all recorded invocations in order
+func (rt *Runtime) Calls() []Call {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	return append([]Call(nil), rt.calls...)
+}
+
+// Count returns how often the given method was called
+func (rt *Runtime) Count(method string) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	n := 0
+
+	for i := range rt.calls {
+		if rt.calls[i].Method == method {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Containers returns the ids of all containers that were not removed yet,
+// in the order they were created
+func (rt *Runtime) Containers() []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	ids := make([]string, 0, len(rt.containers))
+
+	for id := range rt.containers {
+		ids = append(ids, id)
+	}
+
+	// INFO: ids are zero padded counters, sorting them restores the order
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Config returns the configuration the container was started with
+func (rt *Runtime) Config(id string) (dft.RunConfig, bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	c, ok := rt.containers[id]
+	if !ok {
+		return dft.RunConfig{}, false
+	}
+
+	return c.cfg, true
+}
+
+// File returns the content of a file copied into the container
+func (rt *Runtime) File(id string, name string) ([]byte, bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	c, ok := rt.containers[id]
+	if !ok {
+		return nil, false
+	}
+
+	f, ok := c.files[path.Clean(name)]
+	if !ok || f.dir {
+		return nil, false
+	}
+
+	return append([]byte(nil), f.data...), true
+}
+
+// SetState forces the container into the given state, e.g. to simulate a crash
+func (rt *Runtime) SetState(id string, state string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	c, ok := rt.containers[id]
+	if !ok {
+		return errNoSuchContainer(id)
+	}
+
+	c.states = []string{state}
+
+	return nil
+}
+
+// SetPorts replaces the published ports reported for every container,
+// e.g. to simulate new host ports after a restart
+func (rt *Runtime) SetPorts(ports map[uint][]string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.ports = ports
+}
+
+// WriteLog appends output to the stdout logs of the container,
+// followers receive it immediately
+func (rt *Runtime) WriteLog(id string, output string) error {
+	return rt.writeLog(id, logChunk{data: output, time: time.Now()})
+}
+
+// WriteErrLog appends output to the stderr logs of the container,
+// followers receive it immediately
+func (rt *Runtime) WriteErrLog(id string, output string) error {
+	return rt.writeLog(id, logChunk{stderr: true, data: output, time: time.Now()})
+}
+
+func (rt *Runtime) writeLog(id string, chunk logChunk) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	c, ok := rt.containers[id]
+	if !ok {
+		return errNoSuchContainer(id)
+	}
+
+	c.logs = append(c.logs, chunk)
+	c.notify()
+
+	return nil
+}
+
+func (rt *Runtime) record(method string, id string, args ...string) {
+	rt.calls = append(rt.calls, Call{Method: method, ID: id, Args: args})
+}
+
+// lookup returns the container, the caller must hold the lock
+func (rt *Runtime) lookup(id string) (*container, error) {
+	c, ok := rt.containers[id]
+	if !ok {
+		return nil, errNoSuchContainer(id)
+	}
+
+	return c, nil
+}
+
+func errNoSuchContainer(id string) error {
+	return fmt.Errorf("%w: %s", dft.ErrContainerNotFound, id)
+}
+
+func errNoSuchNetwork(name string) error {
+	return fmt.Errorf("no such network: %s", name)
+}
+
+// ip hands out a new address, the caller must hold the lock
+func (rt *Runtime) ip() string {
+	rt.nextIP++
+
+	return fmt.Sprintf("172.18.%d.%d", rt.nextIP/250, rt.nextIP%250+2)
+}
+
+// Networks returns the names of all networks that were not removed yet
+func (rt *Runtime) Networks() []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	names := make([]string, 0, len(rt.networks))
+
+	for name := range rt.networks {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func (rt *Runtime) Create(ctx context.Context, cfg dft.RunConfig) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Create", "", cfg.Image)
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if rt.runErr != nil {
+		return "", rt.runErr
+	}
+
+	if cfg.Network != "" && !rt.networks[cfg.Network] {
+		return "", errNoSuchNetwork(cfg.Network)
+	}
+
+	for id, c := range rt.containers {
+		if cfg.Name != "" && c.cfg.Name == cfg.Name {
+			return "", fmt.Errorf(
+				"%w: name %s is already in use by container %s",
+				dft.ErrNameConflict,
+				cfg.Name,
+				id,
+			)
+		}
+	}
+
+	if cfg.NoPull && !rt.images[cfg.Image] {
+		return "", fmt.Errorf("%w: %s", dft.ErrImageNotPresent, cfg.Image)
+	}
+
+	// like `docker create`, missing images are pulled implicitly
+	rt.images[cfg.Image] = true
+
+	rt.nextID++
+	id := fmt.Sprintf("%012x", rt.nextID)
+
+	c := &container{
+		cfg:      cfg,
+		states:   append([]string(nil), rt.states...),
+		health:   append([]dft.Health(nil), rt.health...),
+		logs:     []logChunk{{data: rt.logs, time: time.Now()}},
+		changed:  make(chan struct{}),
+		networks: map[string]string{},
+		files:    map[string]file{},
+	}
+
+	if cfg.Network != "" {
+		c.networks[cfg.Network] = rt.ip()
+	}
+
+	rt.containers[id] = c
+
+	return id, nil
+}
+
+func (rt *Runtime) Start(ctx context.Context, id string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Start", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if c.started {
+		return fmt.Errorf("container %s was started already", id)
+	}
+
+	c.started = true
+
+	return nil
+}
+
+func (rt *Runtime) Find(
+	ctx context.Context,
+	labels map[string]string,
+) ([]string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Find", "")
+
+	var ids []string
+
+	for id, c := range rt.containers {
+		matches := true
+
+		for k, v := range labels {
+			if c.cfg.Labels[k] != v {
+				matches = false
+
+				break
+			}
+		}
+
+		if matches {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+func (rt *Runtime) State(ctx context.Context, id string) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("State", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return "", err
+	}
+
+	// the scripted states begin once the container was started
+	if !c.started {
+		return StateCreated, nil
+	}
+
+	state := c.states[0]
+	if len(c.states) > 1 {
+		c.states = c.states[1:]
+	}
+
+	return state, nil
+}
+
+func (rt *Runtime) Ports(ctx context.Context, id string) (map[uint][]string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Ports", id)
+
+	if _, err := rt.lookup(id); err != nil {
+		return nil, err
+	}
+
+	ports := make(map[uint][]string, len(rt.ports))
+
+	for k, v := range rt.ports {
+		ports[k] = append([]string(nil), v...)
+	}
+
+	return ports, nil
+}
+
+func (rt *Runtime) Logs(
+	ctx context.Context,
+	id string,
+	opts dft.LogOptions,
+) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Logs", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for _, line := range selectLogs(c.logs, opts, true) {
+		b.WriteString(line.data)
+	}
+
+	return b.String(), nil
+}
+
+// selectLogs splits the chunks into lines and returns the ones selected
+// by the options, the tail is only applied if requested
+func selectLogs(
+	chunks []logChunk,
+	opts dft.LogOptions,
+	tail bool,
+) []logChunk {
+	stdOut, stdErr := opts.Stdout, opts.Stderr
+	if !stdOut && !stdErr {
+		stdOut, stdErr = true, true
+	}
+
+	var lines []logChunk
+
+	for _, chunk := range chunks {
+		if chunk.stderr && !stdErr || !chunk.stderr && !stdOut {
+			continue
+		}
+
+		if !opts.Since.IsZero() && chunk.time.Before(opts.Since) ||
+			!opts.Until.IsZero() && chunk.time.After(opts.Until) {
+			continue
+		}
+
+		for _, line := range strings.SplitAfter(chunk.data, "\n") {
+			if line == "" {
+				continue
+			}
+
+			if opts.Timestamps {
+				line = chunk.time.Format(time.RFC3339Nano) + " " + line
+			}
+
+			lines = append(
+				lines,
+				logChunk{stderr: chunk.stderr, data: line, time: chunk.time},
+			)
+		}
+	}
+
+	if tail && opts.Tail > 0 && len(lines) > opts.Tail {
+		lines = lines[len(lines)-opts.Tail:]
+	}
+
+	return lines
+}
+
+func (rt *Runtime) FollowLogs(
+	ctx context.Context,
+	id string,
+	opts dft.LogOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	rt.mu.Lock()
+	rt.record("FollowLogs", id)
+	rt.mu.Unlock()
+
+	offset := 0
+	restarts := -1
+
+	for {
+		rt.mu.Lock()
+
+		c, err := rt.lookup(id)
+		if err != nil {
+			rt.mu.Unlock()
+
+			return err
+		}
+
+		if restarts < 0 {
+			restarts = c.restarts
+		}
+
+		logs, changed := c.logs[offset:], c.changed
+		stopped := c.stopped || c.restarts != restarts
+		// INFO: the tail only limits the logs written before following
+		tail := offset == 0
+		offset = len(c.logs)
+
+		rt.mu.Unlock()
+
+		lines := selectLogs(logs, opts, tail)
+
+		for i := range lines {
+			w := stdOut
+			if lines[i].stderr {
+				w = stdErr
+			}
+
+			if _, err = io.WriteString(w, lines[i].data); err != nil {
+				return err
+			}
+		}
+
+		if stopped {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (rt *Runtime) Exec(
+	ctx context.Context,
+	id string,
+	cmd []string,
+	opts dft.ExecOptions,
+) (int, error) {
+	rt.mu.Lock()
+
+	rt.record("Exec", id, cmd...)
+
+	c, err := rt.lookup(id)
+	if err == nil && c.stopped {
+		err = fmt.Errorf("container %s is not running", id)
+	}
+
+	fn := rt.execFn
+
+	rt.mu.Unlock()
+
+	if err != nil {
+		return -1, err
+	}
+
+	stdOut, stdErr, code, err := fn(id, cmd)
+
+	if opts.Stdout != nil {
+		_, _ = io.WriteString(opts.Stdout, stdOut)
+	}
+
+	if opts.Stderr != nil {
+		_, _ = io.WriteString(opts.Stderr, stdErr)
+	}
+
+	return code, err
+}
+
+func (rt *Runtime) Inspect(ctx context.Context, id string) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Inspect", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(
+		struct {
+			ID     string        `json:"Id"`
+			State  string        `json:"State"`
+			Config dft.RunConfig `json:"Config"`
+		}{
+			ID:     id,
+			State:  c.states[0],
+			Config: c.cfg,
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (rt *Runtime) Health(ctx context.Context, id string) (dft.Health, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Health", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return dft.Health{}, err
+	}
+
+	if len(c.health) == 0 {
+		return dft.Health{}, nil
+	}
+
+	h := c.health[0]
+	if len(c.health) > 1 {
+		c.health = c.health[1:]
+	}
+
+	return h, nil
+}
+
+func (rt *Runtime) CopyTo(
+	ctx context.Context,
+	id string,
+	dir string,
+	archive io.Reader,
+) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("CopyTo", id, dir)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(archive)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// INFO: like the engines, entries must stay inside of dir
+		if !fs.ValidPath(strings.TrimSuffix(hdr.Name, "/")) {
+			return fmt.Errorf("invalid archive entry %s", hdr.Name)
+		}
+
+		f := file{
+			mode: hdr.FileInfo().Mode(),
+			dir:  hdr.Typeflag == tar.TypeDir,
+		}
+
+		if !f.dir {
+			if f.data, err = io.ReadAll(tr); err != nil {
+				return err
+			}
+		}
+
+		c.files[path.Join(dir, hdr.Name)] = f
+	}
+}
+
+func (rt *Runtime) CopyFrom(
+	ctx context.Context,
+	id string,
+	name string,
+) (io.ReadCloser, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("CopyFrom", id, name)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	name = path.Clean(name)
+
+	// like docker, entries are named relative to the parent of the path
+	var names []string
+
+	for n := range c.files {
+		if n == name || strings.HasPrefix(n, name+"/") {
+			names = append(names, n)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("could not find the file %s in container %s", name, id)
+	}
+
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, n := range names {
+		f := c.files[n]
+
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     strings.TrimPrefix(strings.TrimPrefix(n, path.Dir(name)), "/"),
+			Mode:     int64(f.mode.Perm()),
+			Size:     int64(len(f.data)),
+		}
+
+		if f.dir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		}
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+
+		if _, err = tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(&buf), nil
+}
+
+func (rt *Runtime) Stop(ctx context.Context, id string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Stop", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	c.stopped = true
+	c.states = []string{StateExited}
+	c.notify()
+
+	return nil
+}
+
+func (rt *Runtime) Pause(ctx context.Context, id string) error {
+	return rt.transition("Pause", id, StatePaused)
+}
+
+func (rt *Runtime) Unpause(ctx context.Context, id string) error {
+	return rt.transition("Unpause", id, StateRunning)
+}
+
+func (rt *Runtime) Restart(
+	ctx context.Context,
+	id string,
+	timeout time.Duration,
+) error {
+	err := rt.transition("Restart", id, StateRunning, timeout.String())
+	if err != nil {
+		return err
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if c, err := rt.lookup(id); err == nil {
+		c.restarts++
+		c.notify()
+	}
+
+	return nil
+}
+
+// Kill moves the container into the exited state for SIGKILL and SIGTERM,
+// other signals are only recorded
+func (rt *Runtime) Kill(
+	ctx context.Context,
+	id string,
+	signal syscall.Signal,
+) error {
+	state := ""
+	if signal == syscall.SIGKILL || signal == syscall.SIGTERM {
+		state = StateExited
+	}
+
+	return rt.transition("Kill", id, state, strconv.Itoa(int(signal)))
+}
+
+// transition records the call and moves the running container into the
+// state (if not empty)
+func (rt *Runtime) transition(
+	method string,
+	id string,
+	state string,
+	args ...string,
+) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record(method, id, args...)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if !c.started || c.stopped {
+		return fmt.Errorf("container %s is not running", id)
+	}
+
+	if state != "" {
+		c.states = []string{state}
+		c.notify()
+	}
+
+	return nil
+}
+
+func (rt *Runtime) Remove(ctx context.Context, id string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Remove", id)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	// like docker, only running containers can not be removed
+	exited := c.states[0] == StateExited || c.states[0] == StateDead
+	if c.started && !c.stopped && !exited {
+		return fmt.Errorf("container %s is still running", id)
+	}
+
+	delete(rt.containers, id)
+
+	return nil
+}
+
+func (rt *Runtime) Volumes(ctx context.Context, id string) ([]string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Volumes", id)
+
+	if _, err := rt.lookup(id); err != nil {
+		return nil, err
+	}
+
+	return append([]string{}, rt.volumes...), nil
+}
+
+func (rt *Runtime) RemoveVolumes(ctx context.Context, names []string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("RemoveVolumes", "", names...)
+
+	return nil
+}
+
+func (rt *Runtime) CreateNetwork(
+	ctx context.Context,
+	name string,
+	labels map[string]string,
+) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	args := []string{name}
+
+	for k, v := range labels {
+		args = append(args, k+"="+v)
+	}
+
+	sort.Strings(args[1:])
+
+	rt.record("CreateNetwork", "", args...)
+
+	if rt.networks[name] {
+		return fmt.Errorf("network with name %s already exists", name)
+	}
+
+	rt.networks[name] = true
+
+	return nil
+}
+
+func (rt *Runtime) RemoveNetwork(ctx context.Context, name string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("RemoveNetwork", "", name)
+
+	if !rt.networks[name] {
+		return errNoSuchNetwork(name)
+	}
+
+	for id, c := range rt.containers {
+		if _, ok := c.networks[name]; ok {
+			return fmt.Errorf("network %s has active endpoints: %s", name, id)
+		}
+	}
+
+	delete(rt.networks, name)
+
+	return nil
+}
+
+func (rt *Runtime) ConnectNetwork(
+	ctx context.Context,
+	network string,
+	id string,
+	aliases []string,
+) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("ConnectNetwork", id, append([]string{network}, aliases...)...)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if !rt.networks[network] {
+		return errNoSuchNetwork(network)
+	}
+
+	if _, ok := c.networks[network]; ok {
+		return fmt.Errorf("container %s is already connected to %s", id, network)
+	}
+
+	c.networks[network] = rt.ip()
+
+	return nil
+}
+
+func (rt *Runtime) DisconnectNetwork(
+	ctx context.Context,
+	network string,
+	id string,
+) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("DisconnectNetwork", id, network)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.networks[network]; !ok {
+		return fmt.Errorf("container %s is not connected to %s", id, network)
+	}
+
+	delete(c.networks, network)
+
+	return nil
+}
+
+func (rt *Runtime) IPAddress(
+	ctx context.Context,
+	id string,
+	network string,
+) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("IPAddress", id, network)
+
+	c, err := rt.lookup(id)
+	if err != nil {
+		return "", err
+	}
+
+	ip, ok := c.networks[network]
+	if !ok {
+		return "", fmt.Errorf("container %s is not connected to %s", id, network)
+	}
+
+	return ip, nil
+}
+
+// Images returns the references of all pulled or built images
+// that were not removed yet
+func (rt *Runtime) Images() []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	refs := make([]string, 0, len(rt.images))
+
+	for ref := range rt.images {
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
+
+func (rt *Runtime) Build(
+	ctx context.Context,
+	cfg dft.BuildConfig,
+	w io.Writer,
+) error {
+	rt.mu.Lock()
+	rt.record("Build", "", cfg.Tag)
+	fn := rt.buildFn
+	rt.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := fn(cfg, w); err != nil {
+		return err
+	}
+
+	rt.mu.Lock()
+	rt.images[cfg.Tag] = true
+	rt.mu.Unlock()
+
+	return nil
+}
+
+func (rt *Runtime) RemoveImage(ctx context.Context, ref string) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("RemoveImage", "", ref)
+
+	if !rt.images[ref] {
+		return fmt.Errorf("no such image: %s", ref)
+	}
+
+	delete(rt.images, ref)
+
+	return nil
+}
+
+func (rt *Runtime) Pull(ctx context.Context, ref string, w io.Writer) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("Pull", "", ref)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if rt.pullErr != nil {
+		return rt.pullErr
+	}
+
+	_, _ = fmt.Fprintf(w, "%s: Pull complete\n", ref)
+
+	rt.images[ref] = true
+
+	return nil
+}
+
+func (rt *Runtime) ImageExists(ctx context.Context, ref string) (bool, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.record("ImageExists", "", ref)
+
+	return rt.images[ref], nil
+}
+
+var _ dft.Runtime = (*Runtime)(nil)
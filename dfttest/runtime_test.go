@@ -0,0 +1,45 @@
+package dfttest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestRuntimeContainers(tt *testing.T) {
+	tt.Run(
+		"it lists the containers in creation order",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			var images []string
+
+			// enough containers to notice the random order of a map
+			for i := range 20 {
+				images = append(images, fmt.Sprintf("image-%d", i))
+
+				if _, err := rt.Create(ctx, dft.RunConfig{Image: images[i]}); err != nil {
+					t.Fatalf("[rt.Create] unexpected error: %v", err)
+				}
+			}
+
+			ids := rt.Containers()
+			if len(ids) != len(images) {
+				t.Fatalf("[rt.Containers] unexpected ids: %v", ids)
+			}
+
+			for i := range ids {
+				if cfg, _ := rt.Config(ids[i]); cfg.Image != images[i] {
+					t.Errorf("[rt.Containers] unexpected image at %d: %s", i, cfg.Image)
+				}
+			}
+		},
+	)
+}
@@ -1,7 +1,8 @@
 // Package dft (Docker For Testing) is a lightweight wrapper around docker based
 // on the std lib.
 //
-// Only requirement: A running docker daemon.
+// Only requirement: A running docker daemon (podman and nerdctl are supported
+// as well and picked up automatically if docker is not installed).
 //
 // The package is intended to be used in various testing setups from local testing to
 // CI/CD pipelines. It's main goals are to reduce the need for mocks (especially database ones),
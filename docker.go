@@ -4,40 +4,44 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
 const (
+	actionBuild     = "build"
 	actionContainer = "container"
+	actionCp        = "cp"
+	actionCreate    = "create"
 	actionExec      = "exec"
+	actionImage     = "image"
 	actionInspect   = "inspect"
+	actionLogs      = "logs"
+	actionNetwork   = "network"
 	actionPort      = "port"
-	actionRun       = "run"
+	actionPs        = "ps"
+	actionPull      = "pull"
+	actionStart     = "start"
 	actionVolume    = "volume"
 
-	idLength      = 12
-	intervalAlive = 200
-
-	stateCreated    = "'created'\n"
-	stateDead       = "'dead'\n"
-	stateExited     = "'exited'\n"
-	statePaused     = "'paused'\n"
-	stateRestarting = "'restarting'\n"
-	stateRunning    = "'running'\n"
+	idLength = 12
+	// execHeadLength is the part of the stderr of an exec checked for
+	// errors of the engine
+	execHeadLength = 4096
 )
 
-func startContainer(
+func createContainer(
 	ctx context.Context,
-	imageName string,
-	arguments []string,
-	envVars []string,
-	exposedPorts [][2]uint,
-	mounts [][2]string,
+	bin string,
+	cfg RunConfig,
 ) (string, error) {
 	var (
 		stdOutCapture bytes.Buffer
@@ -48,51 +52,99 @@ func startContainer(
 	// get removed by docker, no need for extensive cleanup
 	// args := []string{runAction, "-d", "--rm"}
 	// INFO: but if we use `--rm`, we loose the ability to dump logs
-	args := []string{actionRun, "-d"}
+	args := []string{actionCreate}
+
+	if cfg.Name != "" {
+		args = append(args, "--name", cfg.Name)
+	}
 
-	for i := range exposedPorts {
+	for i := range cfg.Ports {
 		var seq string
 
-		if exposedPorts[i][1] == 0 {
+		if cfg.Ports[i][1] == 0 {
 			// use random host port to expose container port
-			seq = strconv.FormatUint(uint64(exposedPorts[i][0]), base10)
+			seq = strconv.FormatUint(uint64(cfg.Ports[i][0]), base10)
 		} else {
 			// use specific host port to expose container port
-			seq = strconv.FormatUint(uint64(exposedPorts[i][0]), base10) +
+			seq = strconv.FormatUint(uint64(cfg.Ports[i][0]), base10) +
 				":" +
-				strconv.FormatUint(uint64(exposedPorts[i][1]), base10)
+				strconv.FormatUint(uint64(cfg.Ports[i][1]), base10)
 		}
 
 		args = append(args, "-p", seq)
 	}
 
 	// passing envVars
-	for i := range envVars {
-		args = append(args, "-e", envVars[i])
+	for i := range cfg.Env {
+		args = append(args, "-e", cfg.Env[i])
 	}
 
 	// passing envVars
-	for i := range mounts {
+	for i := range cfg.Mounts {
 		args = append(
 			args,
 			"--mount",
 			fmt.Sprintf(
 				"type=bind,source=%s,target=%s",
-				mounts[i][0],
-				mounts[i][1],
+				cfg.Mounts[i][0],
+				cfg.Mounts[i][1],
 			),
 		)
 	}
 
-	args = append(args, imageName)
+	if cfg.Network != "" {
+		args = append(args, "--network", cfg.Network)
+
+		for i := range cfg.NetworkAliases {
+			args = append(args, "--network-alias", cfg.NetworkAliases[i])
+		}
+	}
+
+	for _, k := range sortedKeys(cfg.Labels) {
+		args = append(args, "--label", k+"="+cfg.Labels[k])
+	}
+
+	for i := range cfg.CapAdd {
+		args = append(args, "--cap-add", cfg.CapAdd[i])
+	}
+
+	if cfg.NoPull {
+		args = append(args, "--pull", "never")
+	}
+
+	if cfg.NoHealthCheck {
+		args = append(args, "--no-healthcheck")
+	}
+
+	if hc := cfg.HealthCheck; hc != nil {
+		args = append(args, "--health-cmd", hc.Cmd)
+
+		if hc.Interval > 0 {
+			args = append(args, "--health-interval", hc.Interval.String())
+		}
+
+		if hc.Timeout > 0 {
+			args = append(args, "--health-timeout", hc.Timeout.String())
+		}
+
+		if hc.Retries > 0 {
+			args = append(args, "--health-retries", strconv.Itoa(hc.Retries))
+		}
+
+		if hc.StartPeriod > 0 {
+			args = append(args, "--health-start-period", hc.StartPeriod.String())
+		}
+	}
+
+	args = append(args, cfg.Image)
 
 	// appending command overwrites
 	// (overwriting dockerfile [CMD])
-	args = append(args, arguments...)
+	args = append(args, cfg.Cmd...)
 
 	cmd := exec.CommandContext(
 		ctx,
-		dockerCmd,
+		bin,
 		args...,
 	)
 
@@ -100,96 +152,188 @@ func startContainer(
 	cmd.Stdout = &stdOutCapture
 
 	err := cmd.Run()
+	if err != nil && isNameConflict(stdErrCapture.String()) {
+		return "", fmt.Errorf(
+			"unable to create container: %w:\n%s\nargs: %q",
+			ErrNameConflict,
+			stdErrCapture.String(),
+			strings.Join(args, " "),
+		)
+	}
+
 	if err != nil {
 		return "", fmt.Errorf(
-			"unable to start container:\n%s\n%s\nargs: %q",
+			"unable to create container:\n%s\n%s\nargs: %q",
 			stdOutCapture.String(),
 			stdErrCapture.String(),
 			strings.Join(args, " "),
 		)
 	}
 
-	return stdOutCapture.String()[:idLength], nil
+	// some engines print pull progress to stdout before the id,
+	// the id is always the last line
+	lines := strings.Split(strings.TrimSpace(stdOutCapture.String()), "\n")
+	id := strings.TrimSpace(lines[len(lines)-1])
+
+	if len(id) < idLength {
+		return "", fmt.Errorf(
+			"unable to read container id:\n%s\nargs: %q",
+			stdOutCapture.String(),
+			strings.Join(args, " "),
+		)
+	}
+
+	return id[:idLength], nil
+}
+
+func startContainer(ctx context.Context, bin string, id string) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionStart, id)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to start container:\n%s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
 }
 
-func containerIsAlive(
+func getState(
 	ctx context.Context,
+	bin string,
 	id string,
-) error {
-	t := time.NewTicker(intervalAlive * time.Millisecond)
-	rdy := make(chan struct{})
-	err := make(chan error)
+) (string, error) {
+	var (
+		stdOutCapture bytes.Buffer
+		stdErrCapture bytes.Buffer
+	)
 
-	go func() {
-		for range t.C {
-			var stdOutCapture bytes.Buffer
-			var stdErrCapture bytes.Buffer
-
-			cmd := exec.CommandContext(
-				ctx,
-				dockerCmd,
-				actionInspect,
-				"-f",
-				"'{{.State.Status}}'",
-				id,
-			)
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		actionInspect,
+		"-f",
+		"{{.State.Status}}",
+		id,
+	)
+
+	cmd.Stdout = &stdOutCapture
+	cmd.Stderr = &stdErrCapture
 
-			cmd.Stdout = &stdOutCapture
-			cmd.Stderr = &stdErrCapture
+	err := cmd.Run()
+	if err != nil && isNoSuchContainer(stdErrCapture.String()) {
+		return "", fmt.Errorf("%w: %s", ErrContainerNotFound, id)
+	}
 
-			cErr := cmd.Run()
-			if cErr != nil {
-				err <- errors.Join(
-					cErr,
-					fmt.Errorf(
-						"unable to inspect container: %s",
-						stdErrCapture.String(),
-					),
-				)
+	if err != nil {
+		return "", errors.Join(
+			err,
+			fmt.Errorf(
+				"unable to inspect container: %s",
+				stdErrCapture.String(),
+			),
+		)
+	}
 
-				t.Stop()
+	return strings.TrimSpace(stdOutCapture.String()), nil
+}
 
-				return
-			}
+// isNoSuchContainer reports if the stderr of the engine says the container
+// does not exist.
+// docker and podman report "no such object" for `inspect`, nerdctl
+// "no such container" (the casing differs between versions)
+func isNoSuchContainer(stdErr string) bool {
+	msg := strings.ToLower(stdErr)
 
-			state := stdOutCapture.String()
+	return strings.Contains(msg, "no such object") ||
+		strings.Contains(msg, "no such container")
+}
 
-			switch state {
-			case stateDead,
-				stateExited,
-				statePaused,
-				stateRestarting:
-				err <- fmt.Errorf(
-					"container in invalid state: %s\nstdOut:%s\nstdErr:%s",
-					state,
-					stdOutCapture.String(),
-					stdErrCapture.String(),
-				)
+// isNameConflict reports if the engine rejected the name of a new container,
+// docker and podman report it as "already in use", nerdctl as "already used"
+func isNameConflict(stdErr string) bool {
+	msg := strings.ToLower(stdErr)
 
-				t.Stop()
+	return strings.Contains(msg, "is already in use") ||
+		strings.Contains(msg, "is already used")
+}
 
-				return
-			case stateRunning:
-				rdy <- struct{}{}
+func inspectContainer(ctx context.Context, bin string, id string) (string, error) {
+	var (
+		stdOutCapture bytes.Buffer
+		stdErrCapture bytes.Buffer
+	)
 
-				t.Stop()
+	cmd := exec.CommandContext(ctx, bin, actionInspect, id)
 
-				return
-			}
-		}
-	}()
+	cmd.Stdout = &stdOutCapture
+	cmd.Stderr = &stdErrCapture
 
-	select {
-	case e := <-err:
-		return e
-	case <-rdy:
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to inspect container: %s",
+			stdErrCapture.String(),
+		)
 	}
 
-	return nil
+	return stdOutCapture.String(), nil
+}
+
+func getHealth(
+	ctx context.Context,
+	bin string,
+	id string,
+) (Health, error) {
+	var (
+		stdOutCapture bytes.Buffer
+		stdErrCapture bytes.Buffer
+	)
+
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		actionInspect,
+		"-f",
+		"{{json .State.Health}}",
+		id,
+	)
+
+	cmd.Stdout = &stdOutCapture
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return Health{}, fmt.Errorf(
+			"unable to inspect container health: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	// containers without a HEALTHCHECK report "null"
+	var h *inspectHealth
+
+	err = json.Unmarshal(stdOutCapture.Bytes(), &h)
+	if err != nil {
+		return Health{}, fmt.Errorf(
+			"unable to parse container health: %w\n%s",
+			err,
+			stdOutCapture.String(),
+		)
+	}
+
+	return h.health(), nil
 }
 
 func getPublishedPorts(
 	ctx context.Context,
+	bin string,
 	id string,
 ) (map[uint][]string, error) {
 	var (
@@ -199,7 +343,7 @@ func getPublishedPorts(
 
 	portMappings := map[uint][]string{}
 
-	cmd := exec.CommandContext(ctx, dockerCmd, actionPort, id)
+	cmd := exec.CommandContext(ctx, bin, actionPort, id)
 
 	cmd.Stderr = &stdErrCapture
 	cmd.Stdout = &stdOutCapture
@@ -217,18 +361,28 @@ func getPublishedPorts(
 	s := bufio.NewScanner(&stdOutCapture)
 
 	for s.Scan() {
-		t := s.Text()
-		parts := strings.Split(t, " -> ")
+		// docker, podman and nerdctl all print "<PORT>/<PROTO> -> <IP>:<PORT>"
+		// but differ in blank lines and the protocols they list
+		internal, addr, found := strings.Cut(s.Text(), " -> ")
+		if !found {
+			continue
+		}
+
+		internal, _, _ = strings.Cut(internal, "/")
+
 		port, pErr := strconv.ParseUint(
-			strings.ReplaceAll(parts[0], "/tcp", ""),
+			strings.TrimSpace(internal),
 			base10,
 			bit64,
 		)
 		if pErr != nil {
-			return nil, err
+			return nil, pErr
 		}
 
-		portMappings[uint(port)] = append(portMappings[uint(port)], parts[1])
+		portMappings[uint(port)] = append(
+			portMappings[uint(port)],
+			strings.TrimSpace(addr),
+		)
 	}
 
 	if err = s.Err(); err != nil {
@@ -238,20 +392,115 @@ func getPublishedPorts(
 	return portMappings, nil
 }
 
-func getLogs(ctx context.Context, id string) (string, error) {
-	out, err := exec.CommandContext(ctx, dockerCmd, "logs", id).CombinedOutput()
+func getLogs(
+	ctx context.Context,
+	bin string,
+	id string,
+	opts LogOptions,
+) (string, error) {
+	var out, skipped bytes.Buffer
+
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		append([]string{actionLogs}, logArgs(id, opts)...)...,
+	)
+
+	// INFO: the CLI replays the streams of the container on its own,
+	// using the same writer for both keeps them in order
+	stdOut, stdErr := opts.streams()
+
+	cmd.Stdout = &skipped
+	if stdOut {
+		cmd.Stdout = &out
+	}
+
+	// errors of the CLI itself end up in stderr as well
+	cmd.Stderr = &skipped
+	if stdErr {
+		cmd.Stderr = &out
+	}
+
+	err := cmd.Run()
 	if err != nil {
 		return "", fmt.Errorf(
-			"unable to retrieve logs for container %s: %w",
+			"unable to retrieve logs for container %s: %w\n%s%s",
+			id,
+			err,
+			out.String(),
+			skipped.String(),
+		)
+	}
+
+	return out.String(), nil
+}
+
+// logArgs returns the arguments of the logs action selecting the logs
+// of the options, the streams are selected by the caller
+func logArgs(id string, opts LogOptions) []string {
+	var args []string
+
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+
+	if !opts.Since.IsZero() {
+		args = append(args, "--since", opts.Since.Format(time.RFC3339Nano))
+	}
+
+	if !opts.Until.IsZero() {
+		args = append(args, "--until", opts.Until.Format(time.RFC3339Nano))
+	}
+
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+
+	return append(args, id)
+}
+
+func followLogs(
+	ctx context.Context,
+	bin string,
+	id string,
+	opts LogOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		append([]string{actionLogs, "--follow"}, logArgs(id, opts)...)...,
+	)
+
+	withStdOut, withStdErr := opts.streams()
+	if !withStdOut {
+		stdOut = io.Discard
+	}
+
+	if !withStdErr {
+		stdErr = io.Discard
+	}
+
+	cmd.Stdout = stdOut
+	cmd.Stderr = io.MultiWriter(stdErr, &stdErrCapture)
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to follow logs for container %s: %w\n%s",
 			id,
 			err,
+			stdErrCapture.String(),
 		)
 	}
 
-	return string(out), nil
+	return nil
 }
 
-func getVolumes(ctx context.Context, id string) ([]string, error) {
+func getVolumes(ctx context.Context, bin string, id string) ([]string, error) {
 	var (
 		stdOutCapture bytes.Buffer
 		stdErrCapture bytes.Buffer
@@ -259,7 +508,7 @@ func getVolumes(ctx context.Context, id string) ([]string, error) {
 
 	cmd := exec.CommandContext(
 		ctx,
-		dockerCmd,
+		bin,
 		actionInspect,
 		"-f",
 		`{{ range .Mounts }}{{if eq .Type "volume"}}{{ .Name }}{{"\n"}}{{ end }}{{ end }}`,
@@ -298,7 +547,7 @@ func getVolumes(ctx context.Context, id string) ([]string, error) {
 	return volumes, nil
 }
 
-func deleteVolumes(ctx context.Context, ids []string) error {
+func deleteVolumes(ctx context.Context, bin string, ids []string) error {
 	var stdErrCapture bytes.Buffer
 
 	args := []string{
@@ -310,7 +559,7 @@ func deleteVolumes(ctx context.Context, ids []string) error {
 
 	cmd := exec.CommandContext( // nolint:gosec
 		ctx,
-		dockerCmd,
+		bin,
 		args...,
 	)
 
@@ -327,17 +576,25 @@ func deleteVolumes(ctx context.Context, ids []string) error {
 	return nil
 }
 
-func stopContainer(ctx context.Context, id string) error {
+func copyArchiveTo(
+	ctx context.Context,
+	bin string,
+	id string,
+	dir string,
+	archive io.Reader,
+) error {
 	var stdErrCapture bytes.Buffer
 
-	cmd := exec.CommandContext(ctx, dockerCmd, actionContainer, "stop", id)
+	cmd := exec.CommandContext(ctx, bin, actionCp, "-", id+":"+dir)
 
+	cmd.Stdin = archive
 	cmd.Stderr = &stdErrCapture
 
 	err := cmd.Run()
 	if err != nil {
 		return fmt.Errorf(
-			"unable to stop container: %s",
+			"unable to copy to %s: %s",
+			dir,
 			stdErrCapture.String(),
 		)
 	}
@@ -345,17 +602,44 @@ func stopContainer(ctx context.Context, id string) error {
 	return nil
 }
 
-func removeContainer(ctx context.Context, id string) error {
+// copyDirTo is used by engines not able to read archives from stdin,
+// the archive is extracted into a temporary directory and copied from there
+func copyDirTo(
+	ctx context.Context,
+	bin string,
+	id string,
+	dir string,
+	archive io.Reader,
+) error {
+	tmp, err := os.MkdirTemp("", "dft-cp-")
+	if err != nil {
+		return fmt.Errorf("unable to copy to %s: %w", dir, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	err = extractTar(archive, tmp)
+	if err != nil {
+		return fmt.Errorf("unable to copy to %s: %w", dir, err)
+	}
+
 	var stdErrCapture bytes.Buffer
 
-	cmd := exec.CommandContext(ctx, dockerCmd, actionContainer, "remove", id)
+	// "<dir>/." copies the content instead of the directory itself
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		actionCp,
+		tmp+string(filepath.Separator)+".",
+		id+":"+dir,
+	)
 
 	cmd.Stderr = &stdErrCapture
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		return fmt.Errorf(
-			"unable to remove container: %s",
+			"unable to copy to %s: %s",
+			dir,
 			stdErrCapture.String(),
 		)
 	}
@@ -363,26 +647,609 @@ func removeContainer(ctx context.Context, id string) error {
 	return nil
 }
 
-func dockerExecute(
+func copyArchiveFrom(
 	ctx context.Context,
+	bin string,
 	id string,
-	command []string,
-) (
-	stdOutCapture bytes.Buffer,
-	stdErrCapture bytes.Buffer,
-	exitCode int,
-	err error,
-) {
-	cmd := exec.CommandContext( // nolint:gosec
+	path string,
+) (io.ReadCloser, error) {
+	stdErrCapture := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, bin, actionCp, id+":"+path, "-")
+
+	cmd.Stderr = stdErrCapture
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy from %s: %w", path, err)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy from %s: %w", path, err)
+	}
+
+	return &cmdReader{
+		ReadCloser: out,
+		cmd:        cmd,
+		stdErr:     stdErrCapture,
+		path:       path,
+	}, nil
+}
+
+// copyDirFrom is used by engines not able to write archives to stdout,
+// the path is copied into a temporary directory and archived from there
+func copyDirFrom(
+	ctx context.Context,
+	bin string,
+	id string,
+	path string,
+) (io.ReadCloser, error) {
+	tmp, err := os.MkdirTemp("", "dft-cp-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy from %s: %w", path, err)
+	}
+
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(
 		ctx,
-		dockerCmd,
-		append([]string{actionExec, id}, command...)...,
+		bin,
+		actionCp,
+		id+":"+path,
+		filepath.Join(tmp, filepath.Base(path)),
 	)
 
 	cmd.Stderr = &stdErrCapture
-	cmd.Stdout = &stdOutCapture
 
 	err = cmd.Run()
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+
+		return nil, fmt.Errorf(
+			"unable to copy from %s: %s",
+			path,
+			stdErrCapture.String(),
+		)
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeTar(pw, dirFS(tmp), "", 0))
+		_ = os.RemoveAll(tmp)
+	}()
+
+	return pr, nil
+}
+
+// cmdReader streams the stdout of a running command,
+// a failure of the command is reported once the output is consumed
+type cmdReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stdErr *bytes.Buffer
+	path   string
+	done   bool
+}
+
+func (r *cmdReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) && !r.done {
+		r.done = true
+
+		if wErr := r.cmd.Wait(); wErr != nil {
+			return n, fmt.Errorf(
+				"unable to copy from %s: %s",
+				r.path,
+				r.stdErr.String(),
+			)
+		}
+	}
+
+	return n, err
+}
+
+func (r *cmdReader) Close() error {
+	if r.done {
+		return nil
+	}
+
+	r.done = true
+
+	// INFO: the output was not consumed, the command may block on writing
+	_ = r.cmd.Process.Kill()
+	_ = r.cmd.Wait()
+
+	return nil
+}
+
+func stopContainer(ctx context.Context, bin string, id string) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionContainer, "stop", id)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to stop container: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func findContainers(
+	ctx context.Context,
+	bin string,
+	labels map[string]string,
+) ([]string, error) {
+	var (
+		stdOutCapture bytes.Buffer
+		stdErrCapture bytes.Buffer
+	)
+
+	args := []string{actionPs, "-a", "-q"}
+
+	for _, k := range sortedKeys(labels) {
+		args = append(args, "--filter", "label="+k+"="+labels[k])
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args...)
+
+	cmd.Stderr = &stdErrCapture
+	cmd.Stdout = &stdOutCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to list containers: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return strings.Fields(stdOutCapture.String()), nil
+}
+
+// containerAction runs a `container` subcommand like pause or kill,
+// the id is part of args
+func containerAction(
+	ctx context.Context,
+	bin string,
+	action string,
+	args ...string,
+) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		append([]string{actionContainer, action}, args...)...,
+	)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to %s container: %s",
+			action,
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func removeContainer(
+	ctx context.Context,
+	bin string,
+	action string,
+	id string,
+) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionContainer, action, id)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to remove container: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func dockerExecute(
+	ctx context.Context,
+	bin string,
+	id string,
+	command []string,
+	opts ExecOptions,
+) (int, error) {
+	args := []string{actionExec}
+
+	if opts.Stdin != nil {
+		args = append(args, "-i")
+	}
+
+	if opts.TTY {
+		args = append(args, "-t")
+	}
+
+	if opts.User != "" {
+		args = append(args, "-u", opts.User)
+	}
+
+	if opts.WorkDir != "" {
+		args = append(args, "-w", opts.WorkDir)
+	}
+
+	for i := range opts.Env {
+		args = append(args, "-e", opts.Env[i])
+	}
+
+	args = append(args, id)
+
+	cmd := exec.CommandContext( // nolint:gosec
+		ctx,
+		bin,
+		append(args, command...)...,
+	)
+
+	// INFO: the engine reports why the command could not be started on
+	// stderr, before the command itself could write anything
+	head := &headWriter{limit: execHeadLength}
+
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = teeWriter(head, opts.Stderr)
+
+	err := cmd.Run()
+
+	// INFO: the exit code of a process that never started is -1
+	code := cmd.ProcessState.ExitCode()
+
+	if code > 0 && isExecStartFailure(head.String()) {
+		return -1, fmt.Errorf(
+			"%w: %s",
+			err,
+			strings.TrimSpace(head.String()),
+		)
+	}
+
+	return code, err
+}
+
+// isExecStartFailure reports if the stderr of an exec comes from the engine
+// failing to start the command (container missing, not running, executable
+// not found, ...) instead of from the command itself
+func isExecStartFailure(stdErr string) bool {
+	msg := strings.ToLower(strings.TrimSpace(stdErr))
+
+	return strings.HasPrefix(msg, "error response from daemon:") ||
+		// docker and podman report missing containers themselves
+		strings.HasPrefix(msg, "error: no such container") ||
+		strings.HasPrefix(msg, "error: no container with name or id") ||
+		// podman: can only create exec sessions on running containers
+		strings.HasPrefix(msg, "error: can only create exec sessions") ||
+		// docker: OCI runtime exec failed, podman: OCI runtime attempted to
+		// invoke a command that was not found
+		strings.Contains(msg, "oci runtime") ||
+		// nerdctl logs its errors with logrus
+		strings.HasPrefix(msg, "fata[") ||
+		strings.Contains(msg, "level=fatal")
+}
+
+// headWriter keeps the first bytes written into it and discards the rest
+type headWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if n := w.limit - w.buf.Len(); n > 0 {
+		w.buf.Write(p[:min(n, len(p))])
+	}
+
+	return len(p), nil
+}
+
+func (w *headWriter) String() string {
+	return w.buf.String()
+}
+
+func createNetwork(
+	ctx context.Context,
+	bin string,
+	name string,
+	labels map[string]string,
+) error {
+	var stdErrCapture bytes.Buffer
+
+	args := []string{actionNetwork, "create"}
+
+	for _, k := range sortedKeys(labels) {
+		args = append(args, "--label", k+"="+labels[k])
+	}
+
+	args = append(args, name)
+
+	cmd := exec.CommandContext(ctx, bin, args...)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to create network: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func removeNetwork(ctx context.Context, bin string, name string) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionNetwork, "rm", name)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to remove network: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func connectNetwork(
+	ctx context.Context,
+	bin string,
+	network string,
+	id string,
+	aliases []string,
+) error {
+	var stdErrCapture bytes.Buffer
+
+	args := []string{actionNetwork, "connect"}
+
+	for i := range aliases {
+		args = append(args, "--alias", aliases[i])
+	}
+
+	args = append(args, network, id)
+
+	cmd := exec.CommandContext(ctx, bin, args...) // nolint:gosec
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to connect container to network: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func disconnectNetwork(
+	ctx context.Context,
+	bin string,
+	network string,
+	id string,
+) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionNetwork, "disconnect", network, id)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to disconnect container from network: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func getIPAddress(
+	ctx context.Context,
+	bin string,
+	id string,
+	network string,
+) (string, error) {
+	var (
+		stdOutCapture bytes.Buffer
+		stdErrCapture bytes.Buffer
+	)
+
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		actionInspect,
+		"-f",
+		"{{json .NetworkSettings.Networks}}",
+		id,
+	)
+
+	cmd.Stdout = &stdOutCapture
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to inspect container networks: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	var networks map[string]struct {
+		IPAddress string `json:"IPAddress"`
+	}
+
+	err = json.Unmarshal(stdOutCapture.Bytes(), &networks)
+	if err != nil {
+		return "", fmt.Errorf(
+			"unable to parse container networks: %w\n%s",
+			err,
+			stdOutCapture.String(),
+		)
+	}
+
+	n, ok := networks[network]
+	if !ok {
+		return "", fmt.Errorf("container is not connected to network %s", network)
+	}
+
+	return n.IPAddress, nil
+}
+
+func buildImage(
+	ctx context.Context,
+	bin string,
+	cfg BuildConfig,
+	w io.Writer,
+) error {
+	// not every engine can read the context from stdin,
+	// so we hand it over as a directory
+	dir, err := os.MkdirTemp("", "dft-build-")
+	if err != nil {
+		return fmt.Errorf("unable to create build context: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = copyToDir(dir, cfg.Context)
+	if err != nil {
+		return fmt.Errorf("unable to create build context: %w", err)
+	}
+
+	args := []string{
+		actionBuild,
+		"-t",
+		cfg.Tag,
+		"-f",
+		filepath.Join(dir, filepath.FromSlash(cfg.Dockerfile)),
+	}
+
+	for _, k := range sortedKeys(cfg.Args) {
+		args = append(args, "--build-arg", k+"="+cfg.Args[k])
+	}
+
+	for _, k := range sortedKeys(cfg.Labels) {
+		args = append(args, "--label", k+"="+cfg.Labels[k])
+	}
+
+	if cfg.Target != "" {
+		args = append(args, "--target", cfg.Target)
+	}
+
+	args = append(args, dir)
+
+	cmd := exec.CommandContext(ctx, bin, args...) // nolint:gosec
+
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to build image: %w\nargs: %q",
+			err,
+			strings.Join(args, " "),
+		)
+	}
+
+	return nil
+}
+
+func removeImage(ctx context.Context, bin string, ref string) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionImage, "rm", ref)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to remove image: %s",
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func pullImage(ctx context.Context, bin string, ref string, w io.Writer) error {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, actionPull, ref)
+
+	cmd.Stdout = w
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to pull image %s: %s",
+			ref,
+			stdErrCapture.String(),
+		)
+	}
+
+	return nil
+}
+
+func imageExists(ctx context.Context, bin string, ref string) (bool, error) {
+	var stdErrCapture bytes.Buffer
+
+	cmd := exec.CommandContext(
+		ctx,
+		bin,
+		actionImage,
+		actionInspect,
+		"--format",
+		"{{.Id}}",
+		ref,
+	)
+
+	cmd.Stderr = &stdErrCapture
+
+	err := cmd.Run()
+	if err != nil {
+		// INFO: the engines word it differently, e.g.
+		// "No such image" (docker) or "image not known" (podman)
+		msg := strings.ToLower(stdErrCapture.String())
+		if strings.Contains(msg, "no such") ||
+			strings.Contains(msg, "not known") ||
+			strings.Contains(msg, "not found") {
+			return false, nil
+		}
+
+		return false, fmt.Errorf(
+			"unable to inspect image %s: %s",
+			ref,
+			stdErrCapture.String(),
+		)
+	}
 
-	return stdOutCapture, stdErrCapture, cmd.ProcessState.ExitCode(), err
+	return true, nil
 }
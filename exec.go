@@ -0,0 +1,107 @@
+package dft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ExecOptions configures a command executed inside of the container
+type ExecOptions struct {
+	// Stdin is streamed into the command, its EOF closes the input
+	Stdin io.Reader
+	// User runs the command as user ("name", "uid" or "uid:gid")
+	User string
+	// WorkDir is the working directory of the command
+	WorkDir string
+	// Env holds additional environment variables ("KEY=value")
+	Env []string
+	// TTY allocates a pseudo terminal, stdErr is merged into stdOut
+	TTY bool
+	// Stdout receives the output while the command runs
+	Stdout io.Writer
+	// Stderr receives the error output while the command runs
+	Stderr io.Writer
+}
+
+// ExecResult is the outcome of a command executed inside of the container
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+}
+
+// ExitError is returned by `Exec` if the command ran but exited with
+// a non-zero code
+type ExitError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// Exec runs the command inside of the container and returns its exit code
+// and captured output.
+//
+// A non-zero exit code returns the result together with an `*ExitError`,
+// any other error means the command could not be executed.
+func (c *Container) Exec(
+	ctx context.Context,
+	cmd []string,
+	opts ExecOptions,
+) (ExecResult, error) {
+	var stdOut, stdErr bytes.Buffer
+
+	eOpts := opts
+	eOpts.Stdout = teeWriter(&stdOut, opts.Stdout)
+	eOpts.Stderr = teeWriter(&stdErr, opts.Stderr)
+
+	start := time.Now()
+
+	code, err := c.rt.Exec(ctx, c.id, cmd, eOpts)
+
+	res := ExecResult{
+		ExitCode: code,
+		Stdout:   stdOut.String(),
+		Stderr:   stdErr.String(),
+		Duration: time.Since(start),
+	}
+
+	return res, execError(cmd, code, err, res.Stderr)
+}
+
+// execError distinguishes commands that could not be executed
+// from commands exiting with a non-zero code
+func execError(cmd []string, code int, err error, stdErr string) error {
+	if code == -1 {
+		if err == nil {
+			err = errors.New("no exit code")
+		}
+
+		return fmt.Errorf("unable to execute %q: %w", cmd, err)
+	}
+
+	if code != 0 {
+		return &ExitError{
+			ExitCode: code,
+			Stderr:   stdErr,
+		}
+	}
+
+	return nil
+}
+
+// teeWriter writes into the capture and w, if set
+func teeWriter(capture io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return capture
+	}
+
+	return io.MultiWriter(capture, w)
+}
@@ -0,0 +1,262 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestExec(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime(
+		dfttest.WithExec(func(_ string, cmd []string) (string, string, int, error) {
+			if cmd[0] == "psql" {
+				return "", "relation does not exist", 1, errors.New("exit status 1")
+			}
+
+			return "migrated", "", 0, nil
+		}),
+	)
+
+	c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+
+	tt.Run(
+		"it returns the captured output",
+		func(t *testing.T) {
+			var out strings.Builder
+
+			res, err := c.Exec(
+				ctx,
+				[]string{"migrate", "up"},
+				dft.ExecOptions{Stdout: &out},
+			)
+			if err != nil {
+				t.Fatalf("[ctr.Exec] unexpected error: %v", err)
+			}
+
+			if res.ExitCode != 0 || res.Stdout != "migrated" {
+				t.Errorf("[ctr.Exec] unexpected result: %+v", res)
+			}
+
+			if out.String() != "migrated" {
+				t.Errorf("[ctr.Exec] output not streamed: %q", out.String())
+			}
+		},
+	)
+
+	tt.Run(
+		"it returns an exit error for non-zero exit codes",
+		func(t *testing.T) {
+			res, err := c.Exec(ctx, []string{"psql", "-f", "seed.sql"}, dft.ExecOptions{})
+
+			var exitErr *dft.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("[ctr.Exec] expected exit error, got: %v", err)
+			}
+
+			if exitErr.ExitCode != 1 || exitErr.Stderr != "relation does not exist" {
+				t.Errorf("[ctr.Exec] unexpected exit error: %+v", exitErr)
+			}
+
+			if res.ExitCode != 1 {
+				t.Errorf("[ctr.Exec] unexpected exit code: %d", res.ExitCode)
+			}
+		},
+	)
+
+	tt.Run(
+		"it fails if the command can not be executed",
+		func(t *testing.T) {
+			if err := c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			res, err := c.Exec(ctx, []string{"migrate", "up"}, dft.ExecOptions{})
+			if err == nil {
+				t.Fatal("[ctr.Exec] expected error")
+			}
+
+			var exitErr *dft.ExitError
+			if errors.As(err, &exitErr) || res.ExitCode != -1 {
+				t.Errorf("[ctr.Exec] unexpected exit error: %v (code %d)", err, res.ExitCode)
+			}
+		},
+	)
+}
+
+func TestExecCLI(tt *testing.T) {
+	stubEngine(tt, "docker", `
+for arg; do last=$arg; done
+
+case "$1" in
+create) echo 0123456789abcdef0123 ;;
+start) echo running > "$dir/state" ;;
+container)
+	case "$2" in
+	stop|kill) echo exited > "$dir/state" ;;
+	esac
+	;;
+inspect)
+	if [ "$3" = "{{.State.Status}}" ]; then
+		read -r state < "$dir/state"
+		echo "$state"
+	fi
+	;;
+exec)
+	read -r state < "$dir/state"
+	if [ "$state" != running ]; then
+		echo "Error response from daemon: container 0123456789ab is not running" >&2
+		exit 1
+	fi
+
+	case "$last" in
+	missing)
+		echo 'OCI runtime exec failed: exec failed: unable to start container process: exec: "missing": executable file not found in $PATH: unknown' >&2
+		exit 127
+		;;
+	nope)
+		echo "sh: 1: nope: not found" >&2
+		exit 127
+		;;
+	esac
+
+	echo "relation does not exist" >&2
+	exit 1
+	;;
+esac
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(dft.NewDockerRuntime()))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	for _, tc := range []struct {
+		name string
+		cmd  []string
+		code int
+	}{
+		{name: "it returns an exit error for non-zero exit codes", cmd: []string{"psql"}, code: 1},
+		{name: "it returns an exit error if the command exits with 127", cmd: []string{"sh", "-c", "nope"}, code: 127},
+		{name: "it fails if the executable is missing", cmd: []string{"missing"}, code: -1},
+	} {
+		tt.Run(
+			tc.name,
+			func(t *testing.T) {
+				res, err := c.Exec(ctx, tc.cmd, dft.ExecOptions{})
+
+				var exitErr *dft.ExitError
+				if errors.As(err, &exitErr) != (tc.code > 0) || res.ExitCode != tc.code {
+					t.Errorf("[ctr.Exec] unexpected error: %v (code %d)", err, res.ExitCode)
+				}
+			},
+		)
+	}
+
+	tt.Run(
+		"it fails if the container is not running",
+		func(t *testing.T) {
+			if err := c.Kill(ctx, syscall.SIGKILL); err != nil {
+				t.Fatalf("[ctr.Kill] unexpected error: %v", err)
+			}
+
+			res, err := c.Exec(ctx, []string{"psql"}, dft.ExecOptions{})
+
+			var exitErr *dft.ExitError
+			if err == nil || errors.As(err, &exitErr) || res.ExitCode != -1 {
+				t.Fatalf("[ctr.Exec] unexpected error: %v (code %d)", err, res.ExitCode)
+			}
+
+			if !strings.Contains(err.Error(), "is not running") {
+				t.Errorf("[ctr.Exec] error lacks the reason: %v", err)
+			}
+		},
+	)
+}
+
+func TestExecDetached(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime(
+		dfttest.WithExec(func(_ string, cmd []string) (string, string, int, error) {
+			if cmd[0] == "kill" {
+				return "", "", 0, nil
+			}
+
+			// the wrapping shell reports its pid first
+			return "consumed 3 messages", "42\nlag 0", 143, errors.New("exit status 143")
+		}),
+	)
+
+	c, err := dft.StartContainer(ctx, "kafka", dft.WithRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	p, err := c.ExecDetached(ctx, []string{"consumer", "--topic", "orders"}, dft.ExecOptions{})
+	if err != nil {
+		tt.Fatalf("[ctr.ExecDetached] unexpected error: %v", err)
+	}
+
+	tt.Run(
+		"it knows the pid inside of the container",
+		func(t *testing.T) {
+			if p.PID() != 42 {
+				t.Errorf("[proc.PID] unexpected pid: %d", p.PID())
+			}
+		},
+	)
+
+	tt.Run(
+		"it signals the process",
+		func(t *testing.T) {
+			if err := p.Kill(ctx); err != nil {
+				t.Fatalf("[proc.Kill] unexpected error: %v", err)
+			}
+
+			calls := rt.Calls()
+			last := calls[len(calls)-1]
+
+			if strings.Join(last.Args, " ") != "kill -9 42" {
+				t.Errorf("[proc.Kill] unexpected command: %v", last.Args)
+			}
+		},
+	)
+
+	tt.Run(
+		"it waits for the exit and hides the pid from the output",
+		func(t *testing.T) {
+			res, err := p.Wait(ctx)
+
+			var exitErr *dft.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode != 143 {
+				t.Errorf("[proc.Wait] expected exit error with code 143, got: %v", err)
+			}
+
+			if res.Stdout != "consumed 3 messages" || res.Stderr != "lag 0" {
+				t.Errorf("[proc.Wait] unexpected result: %+v", res)
+			}
+
+			if stdOut, stdErr := p.Output(); stdOut != res.Stdout || stdErr != res.Stderr {
+				t.Errorf("[proc.Output] unexpected output: %q, %q", stdOut, stdErr)
+			}
+		},
+	)
+}
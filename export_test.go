@@ -0,0 +1,7 @@
+package dft
+
+// StartReaper starts the session reaper for the process instead of the
+// test binary, which outlives every test
+func StartReaper(bin string, pid int) error {
+	return startReaper(bin, pid)
+}
@@ -0,0 +1,61 @@
+package dft
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	modulePath = "github.com/abecodes/dft"
+
+	// labelBuildSession marks the images built by a session, containers
+	// inherit the labels of their image, so it can not be "dft.session"
+	labelBuildSession = "dft.build.session"
+	labelImage        = "dft.image"
+	labelReuse        = "dft.reuse"
+	labelSession      = "dft.session"
+	labelShared       = "dft.shared"
+	labelStarted      = "dft.started"
+	labelVersion      = "dft.version"
+)
+
+// version returns the version of dft the test binary was built with
+var version = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+})
+
+// containerLabels merges the labels of the user with the dft.* ones,
+// which can not be overwritten since the cleanup depends on them
+func containerLabels(imageName string, labels map[string]string) map[string]string {
+	l := make(map[string]string, len(labels)+4)
+
+	for k, v := range labels {
+		l[k] = v
+	}
+
+	for k, v := range sessionLabels() {
+		l[k] = v
+	}
+
+	l[labelImage] = imageName
+	l[labelStarted] = time.Now().UTC().Format(time.RFC3339)
+	l[labelVersion] = version()
+
+	return l
+}
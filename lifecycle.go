@@ -0,0 +1,131 @@
+package dft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// restarts lets log streams resume after a `Restart`
+type restarts struct {
+	mu sync.Mutex
+	n  int
+	// done is closed once the running restart finished,
+	// nil without one
+	done chan struct{}
+}
+
+// begin registers a restart, the returned func marks it as finished
+func (r *restarts) begin() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	done := make(chan struct{})
+
+	r.n++
+	r.done = done
+
+	return func() {
+		r.mu.Lock()
+		if r.done == done {
+			r.done = nil
+		}
+		r.mu.Unlock()
+
+		close(done)
+	}
+}
+
+// count returns the number of restarts so far
+func (r *restarts) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.n
+}
+
+// since reports if the container was restarted after the given count,
+// waiting for a running restart to finish
+func (r *restarts) since(ctx context.Context, n int) bool {
+	r.mu.Lock()
+	restarted, done := r.n != n, r.done
+	r.mu.Unlock()
+
+	if !restarted {
+		return false
+	}
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return true
+}
+
+// Pause freezes all processes of the container, e.g. to simulate
+// a hanging database. Connections stay open but are not served anymore.
+func (c *Container) Pause(ctx context.Context) error {
+	err := c.rt.Pause(ctx, c.id)
+	if err != nil {
+		return err
+	}
+
+	return waitForState(ctx, c.rt, c.id, statePaused)
+}
+
+// Unpause resumes the processes of the paused container
+func (c *Container) Unpause(ctx context.Context) error {
+	err := c.rt.Unpause(ctx, c.id)
+	if err != nil {
+		return err
+	}
+
+	return waitForState(ctx, c.rt, c.id, stateRunning)
+}
+
+// Restart stops the container, killing it if it did not exit within the
+// timeout, and starts it again.
+// It returns once the container is running and refreshes the published
+// ports, since random host ports can change. Reading the ports concurrently
+// (e.g. by a `Proxy`) is safe.
+// Log consumers and `FollowLogs` keep receiving the logs after the restart.
+func (c *Container) Restart(ctx context.Context, timeout time.Duration) error {
+	defer c.restarts.begin()()
+
+	err := c.rt.Restart(ctx, c.id, timeout)
+	if err != nil {
+		return err
+	}
+
+	err = waitForState(ctx, c.rt, c.id, stateRunning)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", c.id, err)
+	}
+
+	if len(c.ports.all()) == 0 {
+		return nil
+	}
+
+	pm, err := waitForPorts(ctx, c.rt, c.id)
+	if err != nil {
+		return fmt.Errorf("[%s] unable to refresh ports: %w", c.id, err)
+	}
+
+	c.ports.set(pm)
+
+	return nil
+}
+
+// Kill sends the signal to the main process of the container,
+// `syscall.SIGKILL` simulates a crash.
+// It does not wait for the container to exit, `Stop` still has to be called
+// to remove it.
+func (c *Container) Kill(ctx context.Context, signal syscall.Signal) error {
+	return c.rt.Kill(ctx, c.id, signal)
+}
@@ -0,0 +1,217 @@
+package dft_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestLifecycle(tt *testing.T) {
+	start := func(t *testing.T) (*dft.Container, *dfttest.Runtime) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		rt := dfttest.NewRuntime(
+			dfttest.WithPorts(map[uint][]string{5432: {"0.0.0.0:32768"}}),
+		)
+
+		c, err := dft.StartContainer(
+			ctx,
+			"postgres",
+			dft.WithRandomPort(5432),
+			dft.WithRuntime(rt),
+		)
+		if err != nil {
+			t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+		}
+
+		t.Cleanup(func() {
+			_ = c.Stop(context.Background())
+		})
+
+		return c, rt
+	}
+
+	tt.Run(
+		"it pauses and unpauses the container",
+		func(t *testing.T) {
+			c, rt := start(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := c.Pause(ctx); err != nil {
+				t.Fatalf("[ctr.Pause] unexpected error: %v", err)
+			}
+
+			if state, _ := rt.State(ctx, rt.Containers()[0]); state != dfttest.StatePaused {
+				t.Errorf("[ctr.Pause] unexpected state: %s", state)
+			}
+
+			if err := c.Unpause(ctx); err != nil {
+				t.Fatalf("[ctr.Unpause] unexpected error: %v", err)
+			}
+
+			if state, _ := rt.State(ctx, rt.Containers()[0]); state != dfttest.StateRunning {
+				t.Errorf("[ctr.Unpause] unexpected state: %s", state)
+			}
+		},
+	)
+
+	tt.Run(
+		"it refreshes the published ports after a restart",
+		func(t *testing.T) {
+			c, rt := start(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt.SetPorts(map[uint][]string{5432: {"0.0.0.0:32770"}})
+
+			// readers like proxies look up the ports during the restart
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+
+				for range 100 {
+					c.ExposedPortAddresses(5432)
+				}
+			}()
+
+			if err := c.Restart(ctx, time.Second); err != nil {
+				t.Fatalf("[ctr.Restart] unexpected error: %v", err)
+			}
+
+			<-done
+
+			prts, ok := c.ExposedPorts(5432)
+			if !ok || len(prts) != 1 || prts[0] != 32770 {
+				t.Errorf("[ctr.ExposedPorts] unexpected ports: %v", prts)
+			}
+		},
+	)
+
+	tt.Run(
+		"it kills the container with the signal",
+		func(t *testing.T) {
+			c, rt := start(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := c.Kill(ctx, syscall.SIGKILL); err != nil {
+				t.Fatalf("[ctr.Kill] unexpected error: %v", err)
+			}
+
+			if state, _ := rt.State(ctx, rt.Containers()[0]); state != dfttest.StateExited {
+				t.Errorf("[ctr.Kill] unexpected state: %s", state)
+			}
+
+			if err := c.Restart(ctx, 0); err != nil {
+				t.Errorf("[ctr.Restart] unexpected error after kill: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it rounds sub-second restart timeouts up",
+		func(t *testing.T) {
+			dir := stubEngine(t, "docker", `
+case "$1" in
+create) echo 0123456789abcdef0123 ;;
+inspect) [ "$3" = "{{.State.Status}}" ] && echo running ;;
+container) [ "$2" = restart ] && echo "$@" > "$dir/restart" ;;
+esac
+exit 0
+`)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(dft.NewDockerRuntime()))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+			defer c.Stop(ctx)
+
+			if err = c.Restart(ctx, 500*time.Millisecond); err != nil {
+				t.Fatalf("[ctr.Restart] unexpected error: %v", err)
+			}
+
+			b, _ := os.ReadFile(filepath.Join(dir, "restart"))
+			if got := strings.TrimSpace(string(b)); got != "container restart -t 1 0123456789ab" {
+				t.Errorf("[ctr.Restart] unexpected arguments: %q", got)
+			}
+		},
+	)
+
+	tt.Run(
+		"it keeps following the logs after a restart",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			var (
+				mu    sync.Mutex
+				lines []string
+			)
+
+			c, err := dft.StartContainer(
+				ctx,
+				"postgres",
+				dft.WithLogConsumer(func(l dft.LogLine) {
+					mu.Lock()
+					lines = append(lines, l.Text)
+					mu.Unlock()
+				}),
+				dft.WithRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			var stdOut bytes.Buffer
+
+			followed := make(chan error, 1)
+			go func() {
+				followed <- c.FollowLogs(ctx, &stdOut, io.Discard)
+			}()
+
+			id := rt.Containers()[0]
+			_ = rt.WriteLog(id, "before\n")
+
+			if err = c.Restart(ctx, time.Second); err != nil {
+				t.Fatalf("[ctr.Restart] unexpected error: %v", err)
+			}
+
+			_ = rt.WriteLog(id, "after\n")
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if strings.Join(lines, ",") != "before,after" {
+				t.Errorf("[dft.WithLogConsumer] unexpected lines: %v", lines)
+			}
+
+			if err = <-followed; err != nil || stdOut.String() != "before\nafter\n" {
+				t.Errorf("[ctr.FollowLogs] unexpected output: %q, %v", stdOut.String(), err)
+			}
+		},
+	)
+}
@@ -0,0 +1,354 @@
+package dft
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// logTailLines is the amount of log lines included in errors
+	logTailLines = 25
+	// logMaxLine is the maximum length of a single log line we can scan
+	logMaxLine = 1024 * 1024
+	// timeoutLogDrain is the time consumers get to receive the last lines
+	// after the container was stopped
+	timeoutLogDrain = time.Second
+	// timeoutLogTail limits the retrieval of logs for error messages
+	timeoutLogTail = 5 * time.Second
+
+	// LogStdout marks lines the container wrote to stdout
+	LogStdout = "stdout"
+	// LogStderr marks lines the container wrote to stderr
+	LogStderr = "stderr"
+)
+
+// LogOptions selects the logs returned by `Container.LogsWithOptions`.
+// The zero value selects all logs of both streams.
+type LogOptions struct {
+	// Tail limits the logs to the last n lines (0: all)
+	Tail int
+	// Since excludes logs written before
+	Since time.Time
+	// Until excludes logs written after
+	Until time.Time
+	// Timestamps prefixes every line with the time it was written
+	// (RFC3339Nano)
+	Timestamps bool
+	// Stdout selects the stdout stream
+	Stdout bool
+	// Stderr selects the stderr stream
+	Stderr bool
+}
+
+// streams returns the selected streams, none selected means both
+func (o LogOptions) streams() (stdOut bool, stdErr bool) {
+	if !o.Stdout && !o.Stderr {
+		return true, true
+	}
+
+	return o.Stdout, o.Stderr
+}
+
+// LogsWithOptions retrieves the logs selected by the options
+func (c *Container) LogsWithOptions(
+	ctx context.Context,
+	opts LogOptions,
+) (string, error) {
+	return c.rt.Logs(ctx, c.id, opts)
+}
+
+// logTail returns the last lines of the logs for error messages,
+// the full logs of chatty containers would bury the actual error
+func logTail(rt Runtime, id string) string {
+	// INFO: the context of the caller has likely expired already
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutLogTail)
+	defer cancel()
+
+	l, err := rt.Logs(ctx, id, LogOptions{Tail: logTailLines + 1})
+	if err != nil {
+		return err.Error()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(l, "\n"), "\n")
+	if len(lines) <= logTailLines {
+		return l
+	}
+
+	return fmt.Sprintf(
+		"[truncated, showing the last %d lines, use Container.Logs for all]\n%s\n",
+		logTailLines,
+		strings.Join(lines[1:], "\n"),
+	)
+}
+
+// LogLine is a single line of the container logs
+type LogLine struct {
+	// Stream is either `LogStdout` or `LogStderr`
+	Stream string
+	// Time is the time the container wrote the line, as reported by
+	// the engine
+	Time time.Time
+	// Text is the line without the trailing newline
+	Text string
+}
+
+// parseLogLine splits the timestamp prefix added by the engine from the line.
+// Lines without a valid prefix are stamped with the time they were received.
+func parseLogLine(stream string, line string) LogLine {
+	l := LogLine{
+		Stream: stream,
+		Time:   time.Now(),
+		Text:   line,
+	}
+
+	prefix, text, ok := strings.Cut(line, " ")
+	if !ok {
+		// INFO: empty lines may come without the separator
+		prefix, text = line, ""
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return l
+	}
+
+	l.Time = t
+	l.Text = text
+
+	return l
+}
+
+// logFollower streams the logs of a container into the consumers
+// registered via `WithLogConsumer`
+type logFollower struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// FollowLogs streams the logs of the container into the writers
+// until the context expires or the container stops, a `Restart` does not
+// end the stream.
+// The logs written so far are included.
+func (c *Container) FollowLogs(
+	ctx context.Context,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	return c.streamLogs(ctx, LogOptions{}, stdOut, stdErr)
+}
+
+// streamLogs follows the logs like `Runtime.FollowLogs`, but resumes after
+// a `Restart`, the engines end the stream once the container stops
+func (c *Container) streamLogs(
+	ctx context.Context,
+	opts LogOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	for {
+		n := c.restarts.count()
+
+		err := c.rt.FollowLogs(ctx, c.id, opts, stdOut, stdErr)
+		if err != nil {
+			return err
+		}
+
+		ended := time.Now()
+
+		if !c.restarts.since(ctx, n) {
+			return nil
+		}
+
+		// INFO: the logs until the stop were streamed already
+		opts.Since = ended
+		opts.Tail = 0
+	}
+}
+
+// followLogs hands every log line to the consumers until the container stops.
+// Consumers are called one at a time.
+func (c *Container) followLogs(consumers []func(LogLine)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := &logFollower{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	c.follower = f
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	scan := func(r io.Reader, stream string) {
+		defer wg.Done()
+
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, logMaxLine)
+
+		for s.Scan() {
+			l := parseLogLine(stream, s.Text())
+
+			mu.Lock()
+			for i := range consumers {
+				consumers[i](l)
+			}
+			mu.Unlock()
+		}
+
+		// INFO: a line exceeding the buffer stops the scanner,
+		// but the follower must never block on a full pipe
+		_, _ = io.Copy(io.Discard, r)
+	}
+
+	outR, outW := io.Pipe()
+	errR, errW := io.Pipe()
+
+	wg.Add(2)
+
+	go scan(outR, LogStdout)
+	go scan(errR, LogStderr)
+
+	go func() {
+		err := c.streamLogs(
+			ctx,
+			LogOptions{Timestamps: true},
+			outW,
+			errW,
+		)
+
+		outW.CloseWithError(err)
+		errW.CloseWithError(err)
+
+		wg.Wait()
+		close(f.done)
+	}()
+}
+
+// stop waits for the consumers to receive the last lines of the stopped
+// container, afterwards no consumer is called anymore
+func (f *logFollower) stop(ctx context.Context) {
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+	case <-time.After(timeoutLogDrain):
+	}
+
+	f.cancel()
+	<-f.done
+}
+
+// WaitForLog blocks until the pattern matched the given number of log lines
+// or the context expires, zero or less occurrences return right away.
+// Unlike `ForLog`, the logs are streamed instead of being fetched repeatedly,
+// which makes it suitable for chatty containers.
+// On failure the error contains the tail of the logs seen so far.
+func (c *Container) WaitForLog(
+	ctx context.Context,
+	pattern *regexp.Regexp,
+	occurrences int,
+) error {
+	if occurrences <= 0 {
+		return nil
+	}
+
+	fCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// INFO: each stream is scanned on its own, writes to a shared pipe
+	// could interleave partial lines of both
+	outR, outW := io.Pipe()
+	errR, errW := io.Pipe()
+
+	// unblocks the follower if we return before the stream ended
+	defer outR.Close()
+	defer errR.Close()
+
+	go func() {
+		err := c.streamLogs(fCtx, LogOptions{}, outW, errW)
+
+		outW.CloseWithError(err)
+		errW.CloseWithError(err)
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		lines    = make(chan string)
+		scanErrs = make([]error, 2)
+	)
+
+	scan := func(r io.Reader, i int) {
+		defer wg.Done()
+
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, logMaxLine)
+
+		for s.Scan() {
+			select {
+			case lines <- s.Text():
+			case <-fCtx.Done():
+				return
+			}
+		}
+
+		scanErrs[i] = s.Err()
+	}
+
+	wg.Add(2)
+
+	go scan(outR, 0)
+	go scan(errR, 1)
+
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	var (
+		found int
+		tail  []string
+	)
+
+	for line := range lines {
+		tail = append(tail, line)
+		if len(tail) > logTailLines {
+			tail = tail[1:]
+		}
+
+		if pattern.MatchString(line) {
+			found++
+		}
+
+		if found >= occurrences {
+			return nil
+		}
+	}
+
+	// the stream ended before we saw enough matches
+	err := ctx.Err()
+	if err == nil {
+		err = errors.Join(scanErrs...)
+	}
+
+	if err == nil {
+		err = errors.New("log stream ended")
+	}
+
+	return fmt.Errorf(
+		"log %q: found %d of %d occurrences: %w\nlast %d log lines:\n%s",
+		pattern,
+		found,
+		occurrences,
+		err,
+		len(tail),
+		strings.Join(tail, "\n"),
+	)
+}
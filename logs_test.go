@@ -0,0 +1,224 @@
+package dft_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestFollowLogs(tt *testing.T) {
+	tt.Run(
+		"it hands separated lines to the consumers until the container stops",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(dfttest.WithLogs("starting\n"))
+
+			var lines []dft.LogLine
+
+			c, err := dft.StartContainer(
+				ctx,
+				"postgres",
+				dft.WithLogConsumer(func(l dft.LogLine) {
+					lines = append(lines, l)
+				}),
+				dft.WithRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			id := rt.Containers()[0]
+			_ = rt.WriteErrLog(id, "WARNING: no password set\n")
+			_ = rt.WriteLog(id, "ready\n")
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(lines) != 3 {
+				t.Fatalf("[dft.WithLogConsumer] unexpected lines: %+v", lines)
+			}
+
+			if lines[1].Stream != dft.LogStderr || lines[1].Text != "WARNING: no password set" {
+				t.Errorf("[dft.WithLogConsumer] unexpected line: %+v", lines[1])
+			}
+
+			if lines[2].Stream != dft.LogStdout || lines[2].Time.IsZero() {
+				t.Errorf("[dft.WithLogConsumer] unexpected line: %+v", lines[2])
+			}
+		},
+	)
+
+	tt.Run(
+		"it stamps lines with the time reported by the engine",
+		func(t *testing.T) {
+			stubEngine(t, "docker", `
+case "$1" in
+create) echo 0123456789abcdef0123 ;;
+inspect) if [ "$3" = "{{.State.Status}}" ]; then echo running; fi ;;
+logs)
+	case " $* " in
+	*" --timestamps "*) ts="2024-01-02T03:04:05.000000006Z " ;;
+	esac
+	echo "${ts}ready"
+	echo "${ts}" >&2
+	;;
+esac
+`)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var (
+				mu    sync.Mutex
+				lines []dft.LogLine
+			)
+
+			c, err := dft.StartContainer(
+				ctx,
+				"postgres",
+				dft.WithLogConsumer(func(l dft.LogLine) {
+					mu.Lock()
+					lines = append(lines, l)
+					mu.Unlock()
+				}),
+				dft.WithRuntime(dft.NewDockerRuntime()),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(lines) != 2 {
+				t.Fatalf("[dft.WithLogConsumer] unexpected lines: %+v", lines)
+			}
+
+			want := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+			for _, l := range lines {
+				if !l.Time.Equal(want) {
+					t.Errorf("[dft.WithLogConsumer] unexpected time: %+v", l)
+				}
+			}
+
+			slices.SortFunc(lines, func(a, b dft.LogLine) int {
+				return strings.Compare(a.Stream, b.Stream)
+			})
+
+			if lines[0].Text != "" || lines[1].Text != "ready" {
+				t.Errorf("[dft.WithLogConsumer] unexpected lines: %+v", lines)
+			}
+		},
+	)
+
+	tt.Run(
+		"it streams stdout and stderr into separate writers",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(dfttest.WithLogs("ready\n"))
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			_ = rt.WriteErrLog(rt.Containers()[0], "oops\n")
+
+			fCtx, fCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer fCancel()
+
+			var stdOut, stdErr bytes.Buffer
+
+			_ = c.FollowLogs(fCtx, &stdOut, &stdErr)
+
+			if stdOut.String() != "ready\n" || stdErr.String() != "oops\n" {
+				t.Errorf("[ctr.FollowLogs] unexpected output: %q, %q", stdOut.String(), stdErr.String())
+			}
+
+			_ = c.Stop(ctx)
+		},
+	)
+}
+
+func TestLogsWithOptions(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime(dfttest.WithLogs("one\ntwo\nthree\n"))
+
+	c, err := dft.StartContainer(ctx, "kafka", dft.WithRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	since := time.Now()
+	_ = rt.WriteErrLog(rt.Containers()[0], "four\n")
+
+	tt.Run(
+		"it returns the tail",
+		func(t *testing.T) {
+			l, err := c.LogsWithOptions(ctx, dft.LogOptions{Tail: 2})
+			if err != nil || l != "three\nfour\n" {
+				t.Errorf("[ctr.LogsWithOptions] unexpected logs: %q, %v", l, err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it selects streams and time ranges",
+		func(t *testing.T) {
+			l, _ := c.LogsWithOptions(ctx, dft.LogOptions{Stdout: true})
+			if l != "one\ntwo\nthree\n" {
+				t.Errorf("[ctr.LogsWithOptions] unexpected stdout: %q", l)
+			}
+
+			l, _ = c.LogsWithOptions(ctx, dft.LogOptions{Since: since, Timestamps: true})
+			if !strings.HasSuffix(l, " four\n") || strings.Contains(l, "three") {
+				t.Errorf("[ctr.LogsWithOptions] unexpected logs since: %q", l)
+			}
+		},
+	)
+}
+
+func TestStartupLogTail(t *testing.T) {
+	var logs strings.Builder
+
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&logs, "line %d\n", i)
+	}
+
+	rt := dfttest.NewRuntime(
+		dfttest.WithStates(dfttest.StateExited),
+		dfttest.WithLogs(logs.String()),
+	)
+
+	_, err := dft.StartContainer(context.Background(), "kafka", dft.WithRuntime(rt))
+	if err == nil {
+		t.Fatal("[dft.StartContainer] expected an error")
+	}
+
+	if !strings.Contains(err.Error(), "truncated") ||
+		!strings.Contains(err.Error(), "line 100") ||
+		strings.Contains(err.Error(), "line 75\n") {
+		t.Errorf("[dft.StartContainer] unexpected logs in error: %v", err)
+	}
+}
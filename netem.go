@@ -0,0 +1,102 @@
+package dft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const netemDevice = "eth0"
+
+// ErrNetemUnsupported is returned by `Degrade` if the image has no `tc`
+// or the container lacks the NET_ADMIN capability
+var ErrNetemUnsupported = errors.New("netem unsupported")
+
+// NetemSpec describes how `Degrade` impairs the network of a container
+type NetemSpec struct {
+	// Delay is added to every outgoing packet
+	Delay time.Duration
+	// Loss is the percentage of dropped packets (e.g. 10 for 10%)
+	Loss float64
+	// Corrupt is the percentage of packets with a flipped bit
+	Corrupt float64
+	// Device is the network interface inside of the container
+	// (default: "eth0")
+	Device string
+}
+
+// args returns the `tc` arguments applying the spec
+func (s NetemSpec) args(device string) []string {
+	args := []string{"tc", "qdisc", "replace", "dev", device, "root", "netem"}
+
+	if s.Delay > 0 {
+		args = append(args, "delay", strconv.FormatInt(s.Delay.Microseconds(), base10)+"us")
+	}
+
+	if s.Loss > 0 {
+		args = append(args, "loss", strconv.FormatFloat(s.Loss, 'f', -1, bit64)+"%")
+	}
+
+	if s.Corrupt > 0 {
+		args = append(args, "corrupt", strconv.FormatFloat(s.Corrupt, 'f', -1, bit64)+"%")
+	}
+
+	return args
+}
+
+// Degrade impairs the outgoing traffic of the container with `tc netem`,
+// a spec without impairments removes them again.
+//
+// The image needs `tc` (iproute2) and the container the NET_ADMIN
+// capability (`WithCapability("NET_ADMIN")`), otherwise
+// `ErrNetemUnsupported` is returned.
+func (c *Container) Degrade(ctx context.Context, spec NetemSpec) error {
+	device := spec.Device
+	if device == "" {
+		device = netemDevice
+	}
+
+	healed := spec.Delay <= 0 && spec.Loss <= 0 && spec.Corrupt <= 0
+
+	cmd := spec.args(device)
+	if healed {
+		cmd = []string{"tc", "qdisc", "del", "dev", device, "root"}
+	}
+
+	res, err := c.Exec(ctx, cmd, ExecOptions{})
+	if err == nil {
+		return nil
+	}
+
+	// INFO: depending on the engine a missing executable fails the exec
+	// itself or exits with 127, docker and podman phrase it differently
+	// ("executable file not found in $PATH", "executable file `tc` not
+	// found in $PATH")
+	msg := res.Stderr
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		msg = err.Error()
+	}
+
+	switch {
+	case res.ExitCode == 127 || strings.Contains(msg, "not found in $PATH"):
+		return fmt.Errorf("%w: tc not found in the image", ErrNetemUnsupported)
+	case strings.Contains(msg, "Operation not permitted"):
+		return fmt.Errorf(
+			"%w: the container lacks the NET_ADMIN capability, use WithCapability(\"NET_ADMIN\")",
+			ErrNetemUnsupported,
+		)
+	// removing impairments that were never applied
+	case healed && (strings.Contains(msg, "handle of zero") ||
+		strings.Contains(msg, "No such file or directory")):
+		return nil
+	case exitErr == nil:
+		return err
+	default:
+		return fmt.Errorf("unable to degrade network: %s", msg)
+	}
+}
@@ -0,0 +1,220 @@
+package dft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Network is a user-defined network containers can join to reach each other
+// by their aliases instead of host-published ports.
+//
+// Networks created via `CreateNetwork` are removed by calling `Remove`,
+// the ones dft creates on its own (e.g. for a `Stack`) once the last
+// container using them was stopped.
+type Network struct {
+	name string
+	rt   Runtime
+	// implicit networks were created by dft and are removed with their
+	// last container
+	implicit bool
+
+	mu      sync.Mutex
+	users   int
+	removed bool
+}
+
+type networkCfg struct {
+	runtime Runtime
+}
+
+// NetworkOption configures `CreateNetwork`
+type NetworkOption func(cfg *networkCfg)
+
+// WithNetworkRuntime sets the container engine used to create the network
+// (default: `DetectRuntime()`)
+func WithNetworkRuntime(rt Runtime) NetworkOption {
+	return func(cfg *networkCfg) {
+		cfg.runtime = rt
+	}
+}
+
+// CreateNetwork creates a new network.
+// If name is empty, a unique name is generated.
+// The network outlives its containers, remove it via `Remove`
+// (e.g. in `t.Cleanup`).
+func CreateNetwork(
+	ctx context.Context,
+	name string,
+	opts ...NetworkOption,
+) (*Network, error) {
+	cfg := networkCfg{
+		runtime: nil,
+	}
+
+	for i := range opts {
+		opts[i](&cfg)
+	}
+
+	rt := cfg.runtime
+	if rt == nil {
+		var err error
+
+		rt, err = defaultRuntime()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newNetwork(ctx, rt, name, false)
+}
+
+// newNetwork creates the network, implicit networks are removed
+// with their last container
+func newNetwork(
+	ctx context.Context,
+	rt Runtime,
+	name string,
+	implicit bool,
+) (*Network, error) {
+	if name == "" {
+		name = "dft-" + randomSuffix()
+	}
+
+	watchSession(rt)
+
+	err := rt.CreateNetwork(ctx, name, sessionLabels())
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", name, err)
+	}
+
+	return &Network{
+		name:     name,
+		rt:       rt,
+		implicit: implicit,
+	}, nil
+}
+
+// Name returns the name of the network
+func (n *Network) Name() string {
+	return n.name
+}
+
+// Remove deletes the network.
+// All containers have to be stopped or disconnected before.
+func (n *Network) Remove(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.remove(ctx)
+}
+
+// remove deletes the network, the caller must hold the lock
+func (n *Network) remove(ctx context.Context) error {
+	if n.removed {
+		return nil
+	}
+
+	err := n.rt.RemoveNetwork(ctx, n.name)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", n.name, err)
+	}
+
+	n.removed = true
+
+	return nil
+}
+
+// acquire registers a container using the network
+func (n *Network) acquire() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.users++
+}
+
+// release unregisters a container and removes an implicit network
+// once it is unused
+func (n *Network) release(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.users--
+	if n.users > 0 || !n.implicit {
+		return nil
+	}
+
+	return n.remove(ctx)
+}
+
+// IPAddress returns the address of the container inside of the given network
+// (e.g. `Network.Name()` or "bridge")
+func (c *Container) IPAddress(ctx context.Context, network string) (string, error) {
+	return c.rt.IPAddress(ctx, c.id, network)
+}
+
+// Connect attaches the running container to the network,
+// making it reachable by the given aliases
+func (c *Container) Connect(
+	ctx context.Context,
+	network *Network,
+	aliases ...string,
+) error {
+	err := c.rt.ConnectNetwork(ctx, network.name, c.id, aliases)
+	if err != nil {
+		return err
+	}
+
+	c.joined(network, aliases)
+
+	return nil
+}
+
+// Disconnect detaches the container from the network.
+// The network is kept until the container is stopped.
+func (c *Container) Disconnect(ctx context.Context, network *Network) error {
+	return c.rt.DisconnectNetwork(ctx, network.name, c.id)
+}
+
+// Partition cuts the container off from the other containers in the network,
+// e.g. a broker from its coordinator, to verify the failover.
+// Connections to and from the container over the network break.
+func (c *Container) Partition(ctx context.Context, network *Network) error {
+	err := c.rt.DisconnectNetwork(ctx, network.name, c.id)
+	if err != nil {
+		return fmt.Errorf("unable to partition from %s: %w", network.name, err)
+	}
+
+	return nil
+}
+
+// Heal reconnects the partitioned container to the network
+// with its previous aliases.
+// The container may get a new IP address inside of the network.
+func (c *Container) Heal(ctx context.Context, network *Network) error {
+	err := c.rt.ConnectNetwork(ctx, network.name, c.id, c.aliases[network.name])
+	if err != nil {
+		return fmt.Errorf("unable to heal partition from %s: %w", network.name, err)
+	}
+
+	return nil
+}
+
+// joined keeps track of the network so it can be released on `Stop`
+func (c *Container) joined(network *Network, aliases []string) {
+	if c.aliases == nil {
+		c.aliases = map[string][]string{}
+	}
+
+	c.aliases[network.name] = aliases
+
+	for i := range c.networks {
+		if c.networks[i] == network {
+			return
+		}
+	}
+
+	network.acquire()
+
+	c.networks = append(c.networks, network)
+}
@@ -0,0 +1,219 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestNetwork(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime()
+
+	backend, err := dft.CreateNetwork(ctx, "", dft.WithNetworkRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.CreateNetwork] unexpected error: %v", err)
+	}
+
+	frontend, err := dft.CreateNetwork(ctx, "frontend", dft.WithNetworkRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.CreateNetwork] unexpected error: %v", err)
+	}
+
+	db, err := dft.StartContainer(
+		ctx,
+		"postgres",
+		dft.WithNetwork(backend, "db"),
+		dft.WithRuntime(rt),
+	)
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+
+	app, err := dft.StartContainer(
+		ctx,
+		"app",
+		dft.WithNetwork(backend, "app"),
+		dft.WithNetwork(frontend),
+		dft.WithRuntime(rt),
+	)
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+
+	tt.Run(
+		"it attaches containers to all networks",
+		func(t *testing.T) {
+			for _, network := range []string{backend.Name(), frontend.Name()} {
+				ip, err := app.IPAddress(ctx, network)
+				if err != nil || ip == "" {
+					t.Errorf("[ctr.IPAddress] unexpected result for %s: %q, %v", network, ip, err)
+				}
+			}
+
+			if _, err := db.IPAddress(ctx, frontend.Name()); err == nil {
+				t.Error("[ctr.IPAddress] expected error for an unconnected network")
+			}
+		},
+	)
+
+	tt.Run(
+		"it can connect and disconnect running containers",
+		func(t *testing.T) {
+			if err := db.Connect(ctx, frontend, "db"); err != nil {
+				t.Fatalf("[ctr.Connect] unexpected error: %v", err)
+			}
+
+			if _, err := db.IPAddress(ctx, frontend.Name()); err != nil {
+				t.Errorf("[ctr.IPAddress] unexpected error: %v", err)
+			}
+
+			if err := db.Disconnect(ctx, frontend); err != nil {
+				t.Fatalf("[ctr.Disconnect] unexpected error: %v", err)
+			}
+
+			if _, err := db.IPAddress(ctx, frontend.Name()); err == nil {
+				t.Error("[ctr.IPAddress] expected error after disconnect")
+			}
+		},
+	)
+
+	tt.Run(
+		"it keeps created networks until they are removed",
+		func(t *testing.T) {
+			if err := app.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if err := db.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Networks()) != 2 {
+				t.Fatalf("[ctr.Stop] networks were removed: %v", rt.Networks())
+			}
+
+			c, err := dft.StartContainer(
+				ctx,
+				"postgres",
+				dft.WithNetwork(backend, "db"),
+				dft.WithRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			for _, n := range []*dft.Network{backend, frontend} {
+				if err = n.Remove(ctx); err != nil {
+					t.Fatalf("[net.Remove] unexpected error: %v", err)
+				}
+			}
+
+			if len(rt.Networks()) != 0 {
+				t.Errorf("[net.Remove] networks were not removed: %v", rt.Networks())
+			}
+		},
+	)
+}
+
+func TestPartition(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime(
+		dfttest.WithExec(func(_ string, cmd []string) (string, string, int, error) {
+			if strings.Join(cmd, " ") == "tc qdisc del dev eth0 root" {
+				return "", "RTNETLINK answers: Operation not permitted\n", 2, errors.New("exit status 2")
+			}
+
+			return "", "", 0, nil
+		}),
+	)
+
+	backend, err := dft.CreateNetwork(ctx, "", dft.WithNetworkRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.CreateNetwork] unexpected error: %v", err)
+	}
+
+	broker, err := dft.StartContainer(
+		ctx,
+		"kafka",
+		dft.WithNetwork(backend, "broker"),
+		dft.WithCapability("NET_ADMIN"),
+		dft.WithRuntime(rt),
+	)
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer broker.Stop(ctx)
+
+	tt.Run(
+		"it partitions and heals the container with its aliases",
+		func(t *testing.T) {
+			if err := broker.Partition(ctx, backend); err != nil {
+				t.Fatalf("[ctr.Partition] unexpected error: %v", err)
+			}
+
+			if _, err := broker.IPAddress(ctx, backend.Name()); err == nil {
+				t.Error("[ctr.IPAddress] expected error after partition")
+			}
+
+			if err := broker.Heal(ctx, backend); err != nil {
+				t.Fatalf("[ctr.Heal] unexpected error: %v", err)
+			}
+
+			calls := rt.Calls()
+			last := calls[len(calls)-1]
+
+			if last.Method != "ConnectNetwork" || strings.Join(last.Args, " ") != backend.Name()+" broker" {
+				t.Errorf("[ctr.Heal] unexpected call: %+v", last)
+			}
+		},
+	)
+
+	tt.Run(
+		"it degrades the network with netem",
+		func(t *testing.T) {
+			cfg, _ := rt.Config(rt.Containers()[0])
+			if len(cfg.CapAdd) != 1 || cfg.CapAdd[0] != "NET_ADMIN" {
+				t.Errorf("[dft.WithCapability] unexpected capabilities: %v", cfg.CapAdd)
+			}
+
+			err := broker.Degrade(
+				ctx,
+				dft.NetemSpec{Delay: 100 * time.Millisecond, Loss: 10, Corrupt: 0.5},
+			)
+			if err != nil {
+				t.Fatalf("[ctr.Degrade] unexpected error: %v", err)
+			}
+
+			calls := rt.Calls()
+			got := strings.Join(calls[len(calls)-1].Args, " ")
+
+			if got != "tc qdisc replace dev eth0 root netem delay 100000us loss 10% corrupt 0.5%" {
+				t.Errorf("[ctr.Degrade] unexpected command: %s", got)
+			}
+		},
+	)
+
+	tt.Run(
+		"it explains missing permissions",
+		func(t *testing.T) {
+			err := broker.Degrade(ctx, dft.NetemSpec{})
+			if !errors.Is(err, dft.ErrNetemUnsupported) || !strings.Contains(err.Error(), "NET_ADMIN") {
+				t.Errorf("[ctr.Degrade] unexpected error: %v", err)
+			}
+		},
+	)
+}
@@ -1,12 +1,46 @@
 package dft
 
-import "strings"
+import (
+	"io/fs"
+	"strings"
+	"time"
+)
 
 type containerCfg struct {
-	args   *[]string
-	env    *[]string
-	mounts *[][2]string
-	ports  *[][2]uint
+	args    *[]string
+	env     *[]string
+	mounts  *[][2]string
+	ports   *[][2]uint
+	runtime Runtime
+	waitFor *[]WaitStrategy
+	// healthCheck overwrites the HEALTHCHECK of the image
+	healthCheck *HealthCheck
+	// noHealthCheck disables the HEALTHCHECK of the image
+	noHealthCheck *bool
+	networks      *[]networkAttachment
+	pullPolicy    *PullPolicy
+	labels        *map[string]string
+	logConsumers  *[]func(LogLine)
+	files         *[]fileInjection
+	capabilities  *[]string
+	reuse         *bool
+	// noSession keeps the session reaper away from the container,
+	// set for containers shared across processes
+	noSession *bool
+}
+
+type networkAttachment struct {
+	network *Network
+	aliases []string
+}
+
+// fileInjection is copied into the container before it starts,
+// either a single file or the content of fsys
+type fileInjection struct {
+	path    string
+	content []byte
+	mode    fs.FileMode
+	fsys    fs.FS
 }
 
 type waitCfg struct {
@@ -94,6 +128,191 @@ func WithRandomPort(port uint) ContainerOption {
 	return WithPort(port, 0)
 }
 
+// WithNetwork attaches the container to the network, making it reachable
+// by the given aliases for other containers in the network.
+// Can be called multiple times.
+func WithNetwork(network *Network, aliases ...string) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.networks == nil {
+			cfg.networks = new([]networkAttachment)
+		}
+
+		n := append(
+			*cfg.networks,
+			networkAttachment{network: network, aliases: aliases},
+		)
+
+		cfg.networks = &n
+	}
+}
+
+// WithWaitStrategy blocks `StartContainer` until all strategies are ready.
+// Can be called multiple times.
+func WithWaitStrategy(strategies ...WaitStrategy) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.waitFor == nil {
+			cfg.waitFor = new([]WaitStrategy)
+		}
+
+		n := append(*cfg.waitFor, strategies...)
+
+		cfg.waitFor = &n
+	}
+}
+
+// WithWaitForHealthy blocks `StartContainer` until the HEALTHCHECK of the
+// container reports "healthy" and fails fast once it reports "unhealthy"
+//
+// (shorthand for `WithWaitStrategy(ForHealthy())`)
+func WithWaitForHealthy() ContainerOption {
+	return WithWaitStrategy(ForHealthy())
+}
+
+// WithHealthCheck overwrites the HEALTHCHECK of the image.
+// The cmd is executed with the default shell of the container,
+// zero durations and retries use the engine defaults.
+func WithHealthCheck(
+	cmd string,
+	interval time.Duration,
+	timeout time.Duration,
+	retries int,
+	startPeriod time.Duration,
+) ContainerOption {
+	return func(cfg *containerCfg) {
+		cfg.healthCheck = &HealthCheck{
+			Cmd:         cmd,
+			Interval:    interval,
+			Timeout:     timeout,
+			Retries:     retries,
+			StartPeriod: startPeriod,
+		}
+		cfg.noHealthCheck = nil
+	}
+}
+
+// WithNoHealthCheck disables the HEALTHCHECK of the image
+func WithNoHealthCheck() ContainerOption {
+	return func(cfg *containerCfg) {
+		b := true
+
+		cfg.noHealthCheck = &b
+		cfg.healthCheck = nil
+	}
+}
+
+// WithFile writes the content into the file inside of the container before
+// it starts, e.g. for images reading their config only at boot.
+// The parent directory has to exist in the image.
+// Can be called multiple times.
+func WithFile(containerPath string, content []byte, mode fs.FileMode) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.files == nil {
+			cfg.files = new([]fileInjection)
+		}
+
+		*cfg.files = append(
+			*cfg.files,
+			fileInjection{path: containerPath, content: content, mode: mode},
+		)
+	}
+}
+
+// WithFS copies the content of fsys (e.g. an `embed.FS`) into the directory
+// inside of the container before it starts.
+// The parent directory has to exist in the image.
+// Can be called multiple times.
+func WithFS(fsys fs.FS, targetDir string) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.files == nil {
+			cfg.files = new([]fileInjection)
+		}
+
+		*cfg.files = append(
+			*cfg.files,
+			fileInjection{path: targetDir, fsys: fsys},
+		)
+	}
+}
+
+// WithLabel adds a label to the container.
+// Can be called multiple times.
+func WithLabel(key string, value string) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.labels == nil {
+			cfg.labels = &map[string]string{}
+		}
+
+		(*cfg.labels)[key] = value
+	}
+}
+
+// WithLabels adds all labels to the container.
+// Can be called multiple times.
+func WithLabels(labels map[string]string) ContainerOption {
+	return func(cfg *containerCfg) {
+		for k, v := range labels {
+			WithLabel(k, v)(cfg)
+		}
+	}
+}
+
+// WithCapability adds a Linux capability to the container (--cap-add),
+// e.g. "NET_ADMIN" for `Degrade`.
+// Can be called multiple times.
+func WithCapability(capability string) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.capabilities == nil {
+			cfg.capabilities = new([]string)
+		}
+
+		*cfg.capabilities = append(*cfg.capabilities, capability)
+	}
+}
+
+// WithReuse adopts a running container started with the same image and
+// options by a previous test run instead of starting a new one, e.g. for
+// slow starting databases while iterating locally.
+// `Stop` keeps reused containers running, `DFT_REUSE=0` disables the reuse
+// (e.g. in CI). It can not be combined with `WithNetwork`.
+// The reuse wins over `PullAlways`: the image is pulled, but an adopted
+// container keeps running the image it was started with.
+func WithReuse() ContainerOption {
+	return func(cfg *containerCfg) {
+		reuse := true
+		cfg.reuse = &reuse
+	}
+}
+
+// WithLogConsumer hands every log line to fn, from the start of the
+// container until `Stop` returns, e.g. to pipe the logs into `t.Log`.
+// Can be called multiple times.
+func WithLogConsumer(fn func(LogLine)) ContainerOption {
+	return func(cfg *containerCfg) {
+		if cfg.logConsumers == nil {
+			cfg.logConsumers = new([]func(LogLine))
+		}
+
+		*cfg.logConsumers = append(*cfg.logConsumers, fn)
+	}
+}
+
+// WithRuntime sets the container engine used to run the container
+// (default: `DetectRuntime()`)
+func WithRuntime(rt Runtime) ContainerOption {
+	return func(cfg *containerCfg) {
+		cfg.runtime = rt
+	}
+}
+
+// WithPullPolicy decides if the image is pulled before the container starts.
+// Without a policy the engine pulls missing images implicitly, which counts
+// against the context of `StartContainer`.
+func WithPullPolicy(policy PullPolicy) ContainerOption {
+	return func(cfg *containerCfg) {
+		cfg.pullPolicy = &policy
+	}
+}
+
 // WithExecuteInsideContainer defines if the wait cmd is executed inside the container
 // or on the host machine
 func WithExecuteInsideContainer(b bool) WaitOption {
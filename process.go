@@ -0,0 +1,272 @@
+package dft
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// detachedScript reports the PID of the shell on stdErr and replaces the shell
+// with the command, so the PID stays valid for signals
+const detachedScript = `echo $$ >&2; exec "$@"`
+
+// Process is a command running in the background inside of the container,
+// started by `ExecDetached`
+type Process struct {
+	c      *Container
+	cmd    []string
+	pid    int
+	stdOut syncBuffer
+	stdErr syncBuffer
+	cancel context.CancelFunc
+	done   chan struct{}
+	res    ExecResult
+	err    error
+}
+
+// ExecDetached starts the command inside of the container and returns once
+// it is running. The context only limits the startup.
+//
+// The image needs `sh` and `kill`. Running processes are stopped
+// with the container.
+func (c *Container) ExecDetached(
+	ctx context.Context,
+	cmd []string,
+	opts ExecOptions,
+) (*Process, error) {
+	// the process outlives the context of the startup
+	execCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	p := &Process{
+		c:      c,
+		cmd:    cmd,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	pidCh := make(chan int, 1)
+
+	eOpts := opts
+	eOpts.Stdout = teeWriter(&p.stdOut, opts.Stdout)
+	eOpts.Stderr = teeWriter(&p.stdErr, opts.Stderr)
+
+	// INFO: with a TTY stdErr is merged into stdOut
+	pw := &pidWriter{pid: pidCh}
+	if opts.TTY {
+		pw.w, eOpts.Stdout = eOpts.Stdout, pw
+	} else {
+		pw.w, eOpts.Stderr = eOpts.Stderr, pw
+	}
+
+	start := time.Now()
+
+	go func() {
+		defer close(p.done)
+
+		code, err := c.rt.Exec(
+			execCtx,
+			c.id,
+			append([]string{"sh", "-c", detachedScript, "dft"}, cmd...),
+			eOpts,
+		)
+
+		p.res = ExecResult{
+			ExitCode: code,
+			Stdout:   p.stdOut.String(),
+			Stderr:   p.stdErr.String(),
+			Duration: time.Since(start),
+		}
+		p.err = execError(cmd, code, err, p.res.Stderr)
+	}()
+
+	select {
+	case p.pid = <-pidCh:
+	case <-p.done:
+		if p.res.ExitCode == -1 {
+			cancel()
+
+			return nil, p.err
+		}
+
+		// the command already exited
+		select {
+		case p.pid = <-pidCh:
+		default:
+		}
+	case <-ctx.Done():
+		cancel()
+		<-p.done
+
+		return nil, fmt.Errorf("unable to start %q: %w", cmd, ctx.Err())
+	}
+
+	c.procs.add(p)
+
+	return p, nil
+}
+
+// PID returns the process id inside of the container
+func (p *Process) PID() int {
+	return p.pid
+}
+
+// Wait blocks until the process exited or the context expires.
+// A non-zero exit code returns the result together with an `*ExitError`.
+func (p *Process) Wait(ctx context.Context) (ExecResult, error) {
+	select {
+	case <-p.done:
+		return p.res, p.err
+	case <-ctx.Done():
+		return ExecResult{ExitCode: -1}, ctx.Err()
+	}
+}
+
+// Signal sends the signal to the process
+func (p *Process) Signal(ctx context.Context, sig syscall.Signal) error {
+	if p.pid == 0 {
+		return fmt.Errorf("unable to signal %q: unknown pid", p.cmd)
+	}
+
+	_, err := p.c.Exec(
+		ctx,
+		[]string{"kill", "-" + strconv.Itoa(int(sig)), strconv.Itoa(p.pid)},
+		ExecOptions{},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to signal %q: %w", p.cmd, err)
+	}
+
+	return nil
+}
+
+// Kill sends SIGKILL to the process
+func (p *Process) Kill(ctx context.Context) error {
+	return p.Signal(ctx, syscall.SIGKILL)
+}
+
+// Output returns the output written by the process so far
+func (p *Process) Output() (stdOut string, stdErr string) {
+	return p.stdOut.String(), p.stdErr.String()
+}
+
+// processes tracks the detached processes of a container
+type processes struct {
+	mu   sync.Mutex
+	list []*Process
+}
+
+func (ps *processes) add(p *Process) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	running := ps.list[:0]
+
+	for i := range ps.list {
+		select {
+		case <-ps.list[i].done:
+		default:
+			running = append(running, ps.list[i])
+		}
+	}
+
+	ps.list = append(running, p)
+}
+
+// kill sends SIGKILL to all running processes, needed if the container
+// keeps running
+func (ps *processes) kill(ctx context.Context) {
+	ps.mu.Lock()
+	list := append([]*Process(nil), ps.list...)
+	ps.mu.Unlock()
+
+	for i := range list {
+		select {
+		case <-list[i].done:
+		default:
+			_ = list[i].Kill(ctx)
+		}
+	}
+}
+
+// stop ends all processes, their exec returns once the container stopped
+func (ps *processes) stop() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for i := range ps.list {
+		ps.list[i].cancel()
+		<-ps.list[i].done
+	}
+
+	ps.list = nil
+}
+
+// pidWriter consumes the first line written by `detachedScript`
+// and passes everything else on
+type pidWriter struct {
+	w     io.Writer
+	pid   chan<- int
+	buf   []byte
+	found bool
+}
+
+func (w *pidWriter) Write(p []byte) (int, error) {
+	if w.found {
+		return w.w.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	i := bytes.IndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	w.found = true
+
+	rest := w.buf[i+1:]
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(w.buf[:i])))
+	if err != nil {
+		// not our line, e.g. the engine failed to start the shell
+		rest = w.buf
+	}
+
+	w.pid <- pid
+	w.buf = nil
+
+	if len(rest) > 0 {
+		_, err = w.w.Write(rest)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// syncBuffer is a buffer that can be read while it is written
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
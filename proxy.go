@@ -0,0 +1,380 @@
+package dft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	proxyBufSize = 32 * 1024
+	// proxySlices splits a second of bandwidth, so limited connections
+	// transfer steadily instead of in bursts
+	proxySlices = 10
+)
+
+// ProxyFaults are the faults a `Proxy` injects, they apply to open
+// connections as well
+type ProxyFaults struct {
+	// Latency delays the data in both directions
+	Latency time.Duration
+	// Jitter adds a random delay of up to the given duration to the latency
+	Jitter time.Duration
+	// Bandwidth limits the bytes per second of each direction of
+	// a connection (0: unlimited)
+	Bandwidth int
+	// Blackhole swallows all data while the connections stay open,
+	// clients run into their timeouts
+	Blackhole bool
+	// DropNew closes new connections right after accepting them
+	DropNew bool
+}
+
+// Proxy is an in-process TCP proxy in front of a port that injects faults
+// like latency or connection resets, e.g. to test the retry logic of clients
+type Proxy struct {
+	l net.Listener
+	// target returns the address new connections are forwarded to
+	target func() (string, error)
+	mu     sync.Mutex
+	faults ProxyFaults
+	links  map[*proxyLink]struct{}
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// proxyLink is a proxied connection
+type proxyLink struct {
+	client   net.Conn
+	upstream net.Conn
+	once     sync.Once
+}
+
+// NewProxy starts a proxy on a random local port forwarding to the target
+// address ("<IP>:<PORT>")
+func NewProxy(target string) (*Proxy, error) {
+	return newProxy(
+		context.Background(),
+		func() (string, error) {
+			return target, nil
+		},
+	)
+}
+
+// newProxy starts a proxy forwarding to the address target returns
+// for every new connection
+func newProxy(ctx context.Context, target func() (string, error)) (*Proxy, error) {
+	var lc net.ListenConfig
+
+	l, err := lc.Listen(ctx, "tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to start proxy: %w", err)
+	}
+
+	p := &Proxy{
+		l:      l,
+		target: target,
+		links:  map[*proxyLink]struct{}{},
+		done:   make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+
+	go p.serve()
+
+	return p, nil
+}
+
+// Proxy starts a proxy in front of the exposed port and returns it,
+// clients connect to `Proxy.Addr()` instead of the container.
+// New connections follow the port if it changes on `Restart`.
+// The proxy is open until it is closed or the container stopped,
+// ctx only bounds the setup of the listener.
+func (c *Container) Proxy(ctx context.Context, port uint) (*Proxy, error) {
+	target := func() (string, error) {
+		addrs, ok := c.ExposedPortAddresses(port)
+		if !ok || len(addrs) == 0 {
+			return "", fmt.Errorf("port %d: not exposed", port)
+		}
+
+		return addrs[0], nil
+	}
+
+	if _, err := target(); err != nil {
+		return nil, err
+	}
+
+	p, err := newProxy(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	c.proxies.add(p)
+
+	return p, nil
+}
+
+// Addr returns the address clients connect to ("127.0.0.1:<PORT>")
+func (p *Proxy) Addr() string {
+	return p.l.Addr().String()
+}
+
+// Faults returns the faults currently injected
+func (p *Proxy) Faults() ProxyFaults {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.faults
+}
+
+// SetFaults replaces the injected faults, `ProxyFaults{}` heals the proxy
+func (p *Proxy) SetFaults(f ProxyFaults) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.faults = f
+}
+
+// ResetConnections resets all open connections,
+// clients see "connection reset by peer"
+func (p *Proxy) ResetConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for l := range p.links {
+		l.close(true)
+	}
+}
+
+// Close stops the proxy and closes all connections
+func (p *Proxy) Close() error {
+	var err error
+
+	p.once.Do(func() {
+		close(p.done)
+
+		err = p.l.Close()
+
+		p.mu.Lock()
+		for l := range p.links {
+			l.close(false)
+		}
+		p.mu.Unlock()
+
+		p.wg.Wait()
+	})
+
+	return err
+}
+
+func (p *Proxy) serve() {
+	defer p.wg.Done()
+
+	for {
+		conn, err := p.l.Accept()
+		if err != nil {
+			// closed
+			return
+		}
+
+		if p.Faults().DropNew {
+			_ = conn.Close()
+
+			continue
+		}
+
+		p.wg.Add(1)
+
+		go p.handle(conn)
+	}
+}
+
+func (p *Proxy) handle(client net.Conn) {
+	defer p.wg.Done()
+
+	d := net.Dialer{Timeout: timeoutProbe}
+
+	target, err := p.target()
+	if err != nil {
+		_ = client.Close()
+
+		return
+	}
+
+	upstream, err := d.Dial("tcp", target)
+	if err != nil {
+		_ = client.Close()
+
+		return
+	}
+
+	l := &proxyLink{
+		client:   client,
+		upstream: upstream,
+	}
+
+	if !p.track(l) {
+		l.close(false)
+
+		return
+	}
+	defer p.untrack(l)
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		p.pipe(l, upstream, client)
+	}()
+
+	go func() {
+		defer wg.Done()
+		p.pipe(l, client, upstream)
+	}()
+
+	wg.Wait()
+	l.close(false)
+}
+
+// track registers the link unless the proxy is closed
+func (p *Proxy) track(l *proxyLink) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+
+	p.links[l] = struct{}{}
+
+	return true
+}
+
+func (p *Proxy) untrack(l *proxyLink) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.links, l)
+}
+
+// pipe copies from src to dst while injecting the faults
+func (p *Proxy) pipe(l *proxyLink, dst net.Conn, src net.Conn) {
+	buf := make([]byte, proxyBufSize)
+
+	for {
+		n := len(buf)
+		if bw := p.Faults().Bandwidth; bw > 0 {
+			n = min(n, max(bw/proxySlices, 1))
+		}
+
+		r, err := src.Read(buf[:n])
+		if r > 0 {
+			// the faults may have changed while waiting for data
+			f := p.Faults()
+
+			delay := f.Latency
+			if f.Jitter > 0 {
+				delay += rand.N(f.Jitter)
+			}
+
+			// the time the data needs on a limited line
+			if f.Bandwidth > 0 {
+				delay += time.Duration(r) * time.Second / time.Duration(f.Bandwidth)
+			}
+
+			if !p.sleep(delay) {
+				return
+			}
+
+			if !f.Blackhole {
+				_, wErr := dst.Write(buf[:r])
+				if wErr != nil {
+					l.close(false)
+
+					return
+				}
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			// INFO: only closing the write side keeps the other direction
+			// open for the response
+			if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+				_ = cw.CloseWrite()
+			}
+
+			return
+		}
+
+		if err != nil {
+			l.close(false)
+
+			return
+		}
+	}
+}
+
+// sleep waits for the duration and reports false if the proxy was closed
+func (p *Proxy) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// close closes both connections, with reset the client receives a RST
+// instead of a FIN
+func (l *proxyLink) close(reset bool) {
+	l.once.Do(func() {
+		if tc, ok := l.client.(*net.TCPConn); ok && reset {
+			_ = tc.SetLinger(0)
+		}
+
+		_ = l.client.Close()
+		_ = l.upstream.Close()
+	})
+}
+
+// proxies tracks the proxies of a container
+type proxies struct {
+	mu   sync.Mutex
+	list []*Proxy
+}
+
+func (ps *proxies) add(p *Proxy) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.list = append(ps.list, p)
+}
+
+// close closes all proxies
+func (ps *proxies) close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for i := range ps.list {
+		_ = ps.list[i].Close()
+	}
+
+	ps.list = nil
+}
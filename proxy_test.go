@@ -0,0 +1,265 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+// echoServer starts a server standing in for a database, echoing all data
+func echoServer(t *testing.T) net.Listener {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("[net.Listen] unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestProxy(tt *testing.T) {
+	l := echoServer(tt)
+
+	p, err := dft.NewProxy(l.Addr().String())
+	if err != nil {
+		tt.Fatalf("[dft.NewProxy] unexpected error: %v", err)
+	}
+	defer p.Close()
+
+	// roundtrip sends the message through the proxy and returns the answer
+	roundtrip := func(t *testing.T, conn net.Conn, msg string) (string, error) {
+		t.Helper()
+
+		_ = conn.SetDeadline(time.Now().Add(time.Second))
+
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return "", err
+		}
+
+		b := make([]byte, len(msg))
+		_, err := io.ReadFull(conn, b)
+
+		return string(b), err
+	}
+
+	dial := func(t *testing.T) net.Conn {
+		t.Helper()
+
+		conn, err := net.Dial("tcp", p.Addr())
+		if err != nil {
+			t.Fatalf("[net.Dial] unexpected error: %v", err)
+		}
+
+		t.Cleanup(func() {
+			conn.Close()
+		})
+
+		return conn
+	}
+
+	tt.Run(
+		"it forwards data",
+		func(t *testing.T) {
+			p.SetFaults(dft.ProxyFaults{})
+
+			got, err := roundtrip(t, dial(t), "SELECT 1")
+			if err != nil || got != "SELECT 1" {
+				t.Errorf("[proxy] unexpected answer: %q, %v", got, err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it adds latency",
+		func(t *testing.T) {
+			p.SetFaults(dft.ProxyFaults{Latency: 100 * time.Millisecond})
+
+			start := time.Now()
+
+			if _, err := roundtrip(t, dial(t), "SELECT 1"); err != nil {
+				t.Fatalf("[proxy] unexpected error: %v", err)
+			}
+
+			// both directions are delayed
+			if d := time.Since(start); d < 200*time.Millisecond {
+				t.Errorf("[proxy] roundtrip took only %s", d)
+			}
+		},
+	)
+
+	tt.Run(
+		"it limits the bandwidth",
+		func(t *testing.T) {
+			p.SetFaults(dft.ProxyFaults{Bandwidth: 10_000})
+
+			start := time.Now()
+
+			if _, err := roundtrip(t, dial(t), strings.Repeat("x", 3_000)); err != nil {
+				t.Fatalf("[proxy] unexpected error: %v", err)
+			}
+
+			if d := time.Since(start); d < 300*time.Millisecond {
+				t.Errorf("[proxy] transfer took only %s", d)
+			}
+		},
+	)
+
+	tt.Run(
+		"it blackholes data",
+		func(t *testing.T) {
+			p.SetFaults(dft.ProxyFaults{Blackhole: true})
+
+			_, err := roundtrip(t, dial(t), "SELECT 1")
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Errorf("[proxy] expected timeout, got: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it drops new connections",
+		func(t *testing.T) {
+			p.SetFaults(dft.ProxyFaults{DropNew: true})
+
+			_, err := roundtrip(t, dial(t), "SELECT 1")
+			if err == nil {
+				t.Error("[proxy] expected dropped connection")
+			}
+		},
+	)
+
+	tt.Run(
+		"it resets open connections",
+		func(t *testing.T) {
+			p.SetFaults(dft.ProxyFaults{})
+
+			conn := dial(t)
+
+			if _, err := roundtrip(t, conn, "SELECT 1"); err != nil {
+				t.Fatalf("[proxy] unexpected error: %v", err)
+			}
+
+			p.ResetConnections()
+
+			_, err := roundtrip(t, conn, "SELECT 1")
+			if !errors.Is(err, syscall.ECONNRESET) {
+				t.Errorf("[proxy] expected connection reset, got: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it proxies an exposed port of a container",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithPorts(map[uint][]string{5432: {l.Addr().String()}}),
+			)
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRandomPort(5432), dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			cp, err := c.Proxy(ctx, 5432)
+			if err != nil {
+				t.Fatalf("[ctr.Proxy] unexpected error: %v", err)
+			}
+
+			conn, err := net.Dial("tcp", cp.Addr())
+			if err != nil {
+				t.Fatalf("[net.Dial] unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			if got, err := roundtrip(t, conn, "SELECT 1"); err != nil || got != "SELECT 1" {
+				t.Errorf("[proxy] unexpected answer: %q, %v", got, err)
+			}
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if _, err := net.Dial("tcp", cp.Addr()); err == nil {
+				t.Error("[ctr.Stop] proxy still accepts connections")
+			}
+		},
+	)
+
+	tt.Run(
+		"it follows the port of a container after a restart",
+		func(t *testing.T) {
+			before, after := echoServer(t), echoServer(t)
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithPorts(map[uint][]string{5432: {before.Addr().String()}}),
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRandomPort(5432), dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			cp, err := c.Proxy(ctx, 5432)
+			if err != nil {
+				t.Fatalf("[ctr.Proxy] unexpected error: %v", err)
+			}
+
+			rt.SetPorts(map[uint][]string{5432: {after.Addr().String()}})
+
+			if err = c.Restart(ctx, time.Second); err != nil {
+				t.Fatalf("[ctr.Restart] unexpected error: %v", err)
+			}
+
+			// the proxy outlives the context it was created with
+			cancel()
+			before.Close()
+
+			conn, err := net.Dial("tcp", cp.Addr())
+			if err != nil {
+				t.Fatalf("[net.Dial] unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			if got, err := roundtrip(t, conn, "SELECT 1"); err != nil || got != "SELECT 1" {
+				t.Errorf("[proxy] unexpected answer: %q, %v", got, err)
+			}
+
+			if err = c.Stop(context.Background()); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,209 @@
+package dft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// PullPolicy decides if `StartContainer` pulls the image before starting it
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image on every start
+	PullAlways PullPolicy = "always"
+	// PullIfNotPresent pulls the image only if it is missing locally
+	PullIfNotPresent PullPolicy = "if-not-present"
+	// PullNever never pulls and fails if the image is missing locally
+	PullNever PullPolicy = "never"
+)
+
+// ErrImageNotPresent is returned if an image is missing locally
+// and the pull policy is `PullNever`
+var ErrImageNotPresent = errors.New("image not present")
+
+type pullCfg struct {
+	output  io.Writer
+	logf    func(format string, args ...any)
+	runtime Runtime
+}
+
+// PullOption configures `PullImage` and `PullImages`
+type PullOption func(cfg *pullCfg)
+
+// WithPullOutput writes the pull progress into w,
+// every line is prefixed with the image reference
+func WithPullOutput(w io.Writer) PullOption {
+	return func(cfg *pullCfg) {
+		cfg.output = w
+	}
+}
+
+// WithPullLogger reports the pull progress line by line to logf,
+// e.g. `log.Printf` or `t.Logf`
+func WithPullLogger(logf func(format string, args ...any)) PullOption {
+	return func(cfg *pullCfg) {
+		cfg.logf = logf
+	}
+}
+
+// WithPullRuntime sets the container engine used to pull the images
+// (default: `DetectRuntime()`)
+func WithPullRuntime(rt Runtime) PullOption {
+	return func(cfg *pullCfg) {
+		cfg.runtime = rt
+	}
+}
+
+// PullImage pulls the image, so a later `StartContainer` does not spend
+// its context on the download
+func PullImage(ctx context.Context, ref string, opts ...PullOption) error {
+	return PullImages(ctx, []string{ref}, opts...)
+}
+
+// PullImages pulls all images concurrently, e.g. in `TestMain`.
+// The returned error contains the failures of all images.
+func PullImages(ctx context.Context, refs []string, opts ...PullOption) error {
+	cfg := pullCfg{
+		output:  nil,
+		logf:    nil,
+		runtime: nil,
+	}
+
+	for i := range opts {
+		opts[i](&cfg)
+	}
+
+	rt := cfg.runtime
+	if rt == nil {
+		var err error
+
+		rt, err = defaultRuntime()
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		wg    sync.WaitGroup
+		outMu sync.Mutex
+		errs  = make([]error, len(refs))
+	)
+
+	for i := range refs {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			w := &progressWriter{
+				prefix: "[" + refs[i] + "] ",
+				mu:     &outMu,
+				out:    cfg.output,
+				logf:   cfg.logf,
+			}
+
+			errs[i] = rt.Pull(ctx, refs[i], w)
+			w.flush()
+		}(i)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// ensureImage makes sure the image is present according to the policy
+func ensureImage(
+	ctx context.Context,
+	rt Runtime,
+	ref string,
+	policy PullPolicy,
+) error {
+	switch policy {
+	case PullAlways:
+		return rt.Pull(ctx, ref, io.Discard)
+	case PullIfNotPresent, PullNever:
+		ok, err := rt.ImageExists(ctx, ref)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		if policy == PullNever {
+			return fmt.Errorf(
+				"%w: %s (pull policy %q)",
+				ErrImageNotPresent,
+				ref,
+				policy,
+			)
+		}
+
+		return rt.Pull(ctx, ref, io.Discard)
+	default:
+		return fmt.Errorf("unknown pull policy %q", policy)
+	}
+}
+
+// progressWriter splits the progress into lines and hands them to the
+// configured output, concurrent pulls share the lock
+type progressWriter struct {
+	prefix string
+	mu     *sync.Mutex
+	out    io.Writer
+	logf   func(format string, args ...any)
+	buf    []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if w.out == nil && w.logf == nil {
+		return len(p), nil
+	}
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.emit(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush emits a trailing line without newline
+func (w *progressWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *progressWriter) emit(line string) {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.out != nil {
+		_, _ = io.WriteString(w.out, w.prefix+line+"\n")
+	}
+
+	if w.logf != nil {
+		w.logf("%s%s", w.prefix, line)
+	}
+}
@@ -0,0 +1,102 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestPull(tt *testing.T) {
+	tt.Run(
+		"it pulls images concurrently and reports the progress",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			var (
+				mu    sync.Mutex
+				lines []string
+			)
+
+			err := dft.PullImages(
+				ctx,
+				[]string{"postgres:16", "redis:7"},
+				dft.WithPullRuntime(rt),
+				dft.WithPullLogger(func(format string, args ...any) {
+					mu.Lock()
+					lines = append(lines, fmt.Sprintf(format, args...))
+					mu.Unlock()
+				}),
+			)
+			if err != nil {
+				t.Fatalf("[dft.PullImages] unexpected error: %v", err)
+			}
+
+			if rt.Count("Pull") != 2 || len(rt.Images()) != 2 {
+				t.Errorf("[dft.PullImages] images were not pulled: %v", rt.Images())
+			}
+
+			if len(lines) != 2 || !strings.HasPrefix(lines[0], "[") {
+				t.Errorf("[dft.PullImages] unexpected progress: %q", lines)
+			}
+		},
+	)
+
+	tt.Run(
+		"it pulls only missing images if not present",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(dfttest.WithImages("postgres:16"))
+
+			for _, img := range []string{"postgres:16", "redis:7"} {
+				c, err := dft.StartContainer(
+					ctx,
+					img,
+					dft.WithPullPolicy(dft.PullIfNotPresent),
+					dft.WithRuntime(rt),
+				)
+				if err != nil {
+					t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+				}
+
+				_ = c.Stop(ctx)
+			}
+
+			if rt.Count("Pull") != 1 {
+				t.Errorf("[dft.StartContainer] unexpected pulls: %d", rt.Count("Pull"))
+			}
+		},
+	)
+
+	tt.Run(
+		"it fails fast for missing images if never pulling",
+		func(t *testing.T) {
+			rt := dfttest.NewRuntime()
+
+			_, err := dft.StartContainer(
+				context.Background(),
+				"postgres:16",
+				dft.WithPullPolicy(dft.PullNever),
+				dft.WithRuntime(rt),
+			)
+			if !errors.Is(err, dft.ErrImageNotPresent) {
+				t.Errorf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if rt.Count("Create") != 0 || rt.Count("Pull") != 0 {
+				t.Error("[dft.StartContainer] container was started or image was pulled")
+			}
+		},
+	)
+}
@@ -0,0 +1,187 @@
+package dft
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// envReuse disables `WithReuse` if set to "0", e.g. in CI
+	envReuse = "DFT_REUSE"
+
+	reusePrefix     = "dft-reuse-"
+	reuseHashLength = 16
+)
+
+// reuseEnabled reports if reusable containers may be adopted
+func reuseEnabled() bool {
+	return os.Getenv(envReuse) != "0"
+}
+
+// reuseHash returns a hash of everything that makes up the container,
+// two containers with the same hash are interchangeable
+func reuseHash(
+	cfg RunConfig,
+	labels map[string]string,
+	files []fileInjection,
+) (string, error) {
+	// the order of the env vars does not change the container
+	env := append([]string(nil), cfg.Env...)
+	sort.Strings(env)
+
+	h := sha256.New()
+
+	err := json.NewEncoder(h).Encode(struct {
+		Image         string
+		Cmd           []string
+		Env           []string
+		Ports         [][2]uint
+		Mounts        [][2]string
+		HealthCheck   *HealthCheck
+		NoHealthCheck bool
+		Labels        map[string]string
+		CapAdd        []string
+	}{
+		Image:         cfg.Image,
+		Cmd:           cfg.Cmd,
+		Env:           env,
+		Ports:         cfg.Ports,
+		Mounts:        cfg.Mounts,
+		HealthCheck:   cfg.HealthCheck,
+		NoHealthCheck: cfg.NoHealthCheck,
+		Labels:        labels,
+		CapAdd:        cfg.CapAdd,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for i := range files {
+		if files[i].fsys == nil {
+			fmt.Fprintf(h, "%s %o\n", files[i].path, files[i].mode)
+			h.Write(files[i].content)
+
+			continue
+		}
+
+		err = fs.WalkDir(
+			files[i].fsys,
+			".",
+			func(p string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+
+				b, err := fs.ReadFile(files[i].fsys, p)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(h, "%s/%s\n", files[i].path, p)
+				h.Write(b)
+
+				return nil
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("unable to hash %s: %w", files[i].path, err)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:reuseHashLength], nil
+}
+
+// findReusable returns the id of a running container with the hash,
+// left over containers which are not running anymore are removed
+func findReusable(ctx context.Context, rt Runtime, hash string) (string, error) {
+	ids, err := rt.Find(ctx, map[string]string{labelReuse: hash})
+	if err != nil {
+		return "", err
+	}
+
+	for i := range ids {
+		state, err := rt.State(ctx, ids[i])
+		if err != nil {
+			return "", err
+		}
+
+		switch state {
+		case stateRunning:
+			return ids[i], nil
+		case stateExited, stateDead, stateCreated:
+			// INFO: the name is still taken by the old container
+			err = removeWithVolumes(ctx, rt, ids[i])
+			if err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf(
+				"reusable container %s in invalid state: '%s'",
+				ids[i],
+				state,
+			)
+		}
+	}
+
+	return "", nil
+}
+
+// awaitReusable returns the id of the container with the hash another
+// process is starting, once it is running
+func awaitReusable(ctx context.Context, rt Runtime, hash string) (string, error) {
+	ids, err := rt.Find(ctx, map[string]string{labelReuse: hash})
+	if err != nil {
+		return "", err
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("reusable container %s%s is gone", reusePrefix, hash)
+	}
+
+	err = waitForState(ctx, rt, ids[0], stateRunning)
+	if err != nil {
+		return "", fmt.Errorf("reusable container %s: %w", ids[0], err)
+	}
+
+	return ids[0], nil
+}
+
+// inspectLabels returns the labels the container was created with,
+// the CLIs return the inspect output as array, the API as single object
+func inspectLabels(ctx context.Context, rt Runtime, id string) (map[string]string, error) {
+	raw, err := rt.Inspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	type inspectConfig struct {
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+	}
+
+	var res []inspectConfig
+
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "[") {
+		raw = "[" + raw + "]"
+	}
+
+	err = json.Unmarshal([]byte(raw), &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read labels of container %s: %w", id, err)
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("unable to read labels of container %s: no inspect output", id)
+	}
+
+	return res[0].Config.Labels, nil
+}
@@ -0,0 +1,186 @@
+package dft_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+// racingRuntime starts the reusable container of another process right
+// after the first lookup missed it
+type racingRuntime struct {
+	*dfttest.Runtime
+
+	once  sync.Once
+	other func()
+}
+
+func (rt *racingRuntime) Find(
+	ctx context.Context,
+	labels map[string]string,
+) ([]string, error) {
+	ids, err := rt.Runtime.Find(ctx, labels)
+
+	rt.once.Do(rt.other)
+
+	return ids, err
+}
+
+func TestReuse(tt *testing.T) {
+	// start starts and stops a reusable container
+	start := func(t *testing.T, rt *dfttest.Runtime, opts ...dft.ContainerOption) *dft.Container {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		c, err := dft.StartContainer(
+			ctx,
+			"elasticsearch",
+			append(
+				[]dft.ContainerOption{
+					dft.WithEnvVar("discovery.type", "single-node"),
+					dft.WithRandomPort(9200),
+					dft.WithReuse(),
+					dft.WithRuntime(rt),
+				},
+				opts...,
+			)...,
+		)
+		if err != nil {
+			t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+		}
+
+		prts, ok := c.ExposedPorts(9200)
+		if !ok || len(prts) != 1 || prts[0] != 32768 {
+			t.Errorf("[ctr.ExposedPorts] unexpected ports: %v", prts)
+		}
+
+		if err = c.Stop(ctx); err != nil {
+			t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+		}
+
+		return c
+	}
+
+	newRuntime := func() *dfttest.Runtime {
+		return dfttest.NewRuntime(
+			dfttest.WithPorts(map[uint][]string{9200: {"0.0.0.0:32768"}}),
+			dfttest.WithVolumes("data"),
+		)
+	}
+
+	tt.Run(
+		"it adopts a running container with the same options",
+		func(t *testing.T) {
+			rt := newRuntime()
+
+			start(t, rt)
+			c := start(t, rt)
+
+			if rt.Count("Create") != 1 || len(rt.Containers()) != 1 {
+				t.Fatalf(
+					"[dft.WithReuse] container was not reused (%d created, %d left)",
+					rt.Count("Create"),
+					len(rt.Containers()),
+				)
+			}
+
+			cfg, _ := rt.Config(rt.Containers()[0])
+			if !strings.HasPrefix(cfg.Name, "dft-reuse-") || cfg.Labels["dft.reuse"] == "" {
+				t.Errorf("[dft.WithReuse] unexpected name and labels: %s, %v", cfg.Name, cfg.Labels)
+			}
+
+			// the reaper of the session must not remove it
+			if _, ok := cfg.Labels["dft.session"]; ok {
+				t.Errorf("[dft.WithReuse] unexpected session label: %v", cfg.Labels)
+			}
+
+			// e.g. dft.started is the start of the adopted container
+			if rt.Count("Inspect") != 1 || !reflect.DeepEqual(c.Labels(), cfg.Labels) {
+				t.Errorf("[ctr.Labels] unexpected labels of the adopted container: %v", c.Labels())
+			}
+		},
+	)
+
+	tt.Run(
+		"it adopts the container another process created concurrently",
+		func(t *testing.T) {
+			rt := newRuntime()
+			racing := &racingRuntime{Runtime: rt}
+			racing.other = func() { start(t, rt) }
+
+			start(t, rt, dft.WithRuntime(racing))
+
+			// the second create was rejected for the name
+			if rt.Count("Create") != 2 || len(rt.Containers()) != 1 {
+				t.Fatalf(
+					"[dft.WithReuse] container was not adopted (%d created, %d left)",
+					rt.Count("Create"),
+					len(rt.Containers()),
+				)
+			}
+
+			if rt.Count("Start") != 1 {
+				t.Errorf("[dft.WithReuse] container was started %d times", rt.Count("Start"))
+			}
+		},
+	)
+
+	tt.Run(
+		"it starts a new container if the options differ",
+		func(t *testing.T) {
+			rt := newRuntime()
+
+			start(t, rt)
+			start(t, rt, dft.WithEnvVar("ES_JAVA_OPTS", "-Xmx512m"))
+
+			if rt.Count("Create") != 2 || len(rt.Containers()) != 2 {
+				t.Errorf("[dft.WithReuse] unexpected containers: %v", rt.Containers())
+			}
+		},
+	)
+
+	tt.Run(
+		"it replaces containers which are not running anymore",
+		func(t *testing.T) {
+			rt := newRuntime()
+
+			start(t, rt)
+
+			old := rt.Containers()[0]
+			_ = rt.SetState(old, dfttest.StateExited)
+
+			start(t, rt)
+
+			if ids := rt.Containers(); len(ids) != 1 || ids[0] == old {
+				t.Errorf("[dft.WithReuse] exited container was not replaced: %v", ids)
+			}
+
+			if rt.Count("RemoveVolumes") != 1 {
+				t.Errorf("[dft.WithReuse] volumes of the exited container were not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it can be disabled via DFT_REUSE=0",
+		func(t *testing.T) {
+			t.Setenv("DFT_REUSE", "0")
+
+			rt := newRuntime()
+
+			start(t, rt)
+
+			if len(rt.Containers()) != 0 {
+				t.Errorf("[ctr.Stop] container was not removed: %v", rt.Containers())
+			}
+		},
+	)
+}
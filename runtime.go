@@ -0,0 +1,493 @@
+package dft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrContainerNotFound is returned (wrapped) by `Runtime.State`
+// if the container does not exist
+var ErrContainerNotFound = errors.New("container not found")
+
+// ErrNameConflict is returned (wrapped) by `Runtime.Create`
+// if the name is taken by another container
+var ErrNameConflict = errors.New("container name already in use")
+
+// Runtime is the container engine dft talks to.
+//
+// The default implementation shells out to the `docker` CLI, but any engine
+// (or a fake for unit tests) can be plugged in via `WithRuntime`.
+type Runtime interface {
+	// Create creates a container without starting it and returns its id,
+	// `ErrNameConflict` if the name is taken
+	Create(ctx context.Context, cfg RunConfig) (string, error)
+	// Start starts the created container
+	Start(ctx context.Context, id string) error
+	// Find returns the ids of all containers (in any state) having all labels
+	Find(ctx context.Context, labels map[string]string) ([]string, error)
+	// State returns the status of the container
+	// (created, running, paused, restarting, exited, dead),
+	// `ErrContainerNotFound` if it does not exist
+	State(ctx context.Context, id string) (string, error)
+	// Ports returns the published ports of the container mapped to a list
+	// of "<IP>:<PORT>" host addresses
+	Ports(ctx context.Context, id string) (map[uint][]string, error)
+	// Logs returns the output of the container selected by the options
+	Logs(ctx context.Context, id string, opts LogOptions) (string, error)
+	// FollowLogs streams the output of the container selected by the
+	// options into the writers until the context expires or the container
+	// stops
+	FollowLogs(
+		ctx context.Context,
+		id string,
+		opts LogOptions,
+		stdOut io.Writer,
+		stdErr io.Writer,
+	) error
+	// Exec runs the command inside of the container and streams its output
+	// into the writers of the options.
+	// An exit code of -1 indicates that the command could not be executed,
+	// e.g. because the container is not running or the executable is missing.
+	Exec(
+		ctx context.Context,
+		id string,
+		cmd []string,
+		opts ExecOptions,
+	) (exitCode int, err error)
+	// Inspect returns the raw inspect output (JSON) of the container
+	Inspect(ctx context.Context, id string) (string, error)
+	// Health returns the HEALTHCHECK state of the container
+	Health(ctx context.Context, id string) (Health, error)
+	// CopyTo extracts the tar archive into the directory inside of the
+	// container
+	CopyTo(ctx context.Context, id string, dir string, archive io.Reader) error
+	// CopyFrom returns the file or directory inside of the container as tar
+	// archive, the caller has to close it
+	CopyFrom(ctx context.Context, id string, path string) (io.ReadCloser, error)
+	// Stop stops the container
+	Stop(ctx context.Context, id string) error
+	// Pause freezes all processes of the container
+	Pause(ctx context.Context, id string) error
+	// Unpause resumes the processes of the paused container
+	Unpause(ctx context.Context, id string) error
+	// Restart stops the container, killing it after the timeout,
+	// and starts it again
+	Restart(ctx context.Context, id string, timeout time.Duration) error
+	// Kill sends the signal to the main process of the container
+	Kill(ctx context.Context, id string, signal syscall.Signal) error
+	// Remove removes the stopped container
+	Remove(ctx context.Context, id string) error
+	// Volumes returns the names of the volumes mounted into the container
+	Volumes(ctx context.Context, id string) ([]string, error)
+	// RemoveVolumes deletes the given volumes
+	RemoveVolumes(ctx context.Context, names []string) error
+	// CreateNetwork creates a user-defined network
+	CreateNetwork(ctx context.Context, name string, labels map[string]string) error
+	// RemoveNetwork deletes the network
+	RemoveNetwork(ctx context.Context, name string) error
+	// ConnectNetwork attaches the running container to the network,
+	// making it reachable by the given aliases
+	ConnectNetwork(
+		ctx context.Context,
+		network string,
+		id string,
+		aliases []string,
+	) error
+	// DisconnectNetwork detaches the container from the network
+	DisconnectNetwork(ctx context.Context, network string, id string) error
+	// IPAddress returns the address of the container inside of the network
+	IPAddress(ctx context.Context, id string, network string) (string, error)
+	// Build builds an image, writing the build output into w
+	Build(ctx context.Context, cfg BuildConfig, w io.Writer) error
+	// RemoveImage deletes the image
+	RemoveImage(ctx context.Context, ref string) error
+	// Pull pulls the image, writing the progress into w
+	Pull(ctx context.Context, ref string, w io.Writer) error
+	// ImageExists reports if the image is present locally
+	ImageExists(ctx context.Context, ref string) (bool, error)
+}
+
+// RunConfig describes the container a `Runtime` should run
+type RunConfig struct {
+	// Name is the name of the container, the engine generates one if empty
+	Name string
+	// Image is the image the container is created from
+	Image string
+	// Cmd overwrites the [CMD] of the image
+	Cmd []string
+	// Env is a list of "<KEY>=<VALUE>" pairs
+	Env []string
+	// Ports is a list of [internal, host] port pairs, a host port of 0
+	// selects a random port
+	Ports [][2]uint
+	// Mounts is a list of [source, target] bind mounts
+	Mounts [][2]string
+	// HealthCheck overwrites the HEALTHCHECK of the image
+	HealthCheck *HealthCheck
+	// NoHealthCheck disables the HEALTHCHECK of the image
+	NoHealthCheck bool
+	// Network is the network the container is attached to on start
+	Network string
+	// NetworkAliases are the names the container is reachable by
+	// inside of the network
+	NetworkAliases []string
+	// Labels are added to the container
+	Labels map[string]string
+	// CapAdd are Linux capabilities added to the container (e.g. "NET_ADMIN")
+	CapAdd []string
+	// NoPull fails if the image is missing instead of pulling it implicitly
+	NoPull bool
+}
+
+// BuildConfig describes the image a `Runtime` should build
+type BuildConfig struct {
+	// Context holds the files of the build context
+	Context fs.FS
+	// Dockerfile is the path of the Dockerfile inside of the context
+	Dockerfile string
+	// Args are passed as --build-arg
+	Args map[string]string
+	// Target is the build stage to build
+	Target string
+	// Labels are added to the image
+	Labels map[string]string
+	// Tag is the reference of the resulting image
+	Tag string
+}
+
+// HealthCheck describes a HEALTHCHECK, zero values use the engine defaults
+type HealthCheck struct {
+	// Cmd is executed with the default shell of the container,
+	// an exit code of 0 marks the container healthy
+	Cmd         string
+	Interval    time.Duration
+	Timeout     time.Duration
+	Retries     int
+	StartPeriod time.Duration
+}
+
+// Health is the HEALTHCHECK state of a container
+type Health struct {
+	// Status is one of "starting", "healthy" or "unhealthy".
+	// It is empty if the container has no HEALTHCHECK.
+	Status string
+	// Log holds the output of the most recent probes
+	Log []string
+}
+
+// inspectHealth is the `.State.Health` part of an inspect result
+type inspectHealth struct {
+	Status string `json:"Status"`
+	Log    []struct {
+		ExitCode int    `json:"ExitCode"`
+		Output   string `json:"Output"`
+	} `json:"Log"`
+}
+
+func (h *inspectHealth) health() Health {
+	if h == nil {
+		return Health{}
+	}
+
+	hlth := Health{
+		Status: h.Status,
+		Log:    make([]string, 0, len(h.Log)),
+	}
+
+	for i := range h.Log {
+		hlth.Log = append(hlth.Log, h.Log[i].Output)
+	}
+
+	return hlth
+}
+
+// cliRuntime implements `Runtime` by executing a docker compatible binary
+type cliRuntime struct {
+	bin string
+	// rmAction is the subcommand of `container` removing a container
+	rmAction string
+	// states maps engine specific states onto the ones docker reports
+	states map[string]string
+	// cpArchive indicates if `cp` can read and write tar archives
+	// via stdin and stdout ("-")
+	cpArchive bool
+}
+
+// NewDockerRuntime returns a `Runtime` based on the `docker` CLI.
+// This is the default runtime.
+func NewDockerRuntime() Runtime {
+	return &cliRuntime{
+		bin:       dockerCmd,
+		rmAction:  "remove",
+		states:    nil,
+		cpArchive: true,
+	}
+}
+
+// NewPodmanRuntime returns a `Runtime` based on the `podman` CLI.
+// Rootless setups are supported as long as the published ports are allowed
+// for the user.
+func NewPodmanRuntime() Runtime {
+	return &cliRuntime{
+		bin:      podmanCmd,
+		rmAction: "rm",
+		states: map[string]string{
+			"configured":  stateCreated,
+			"initialized": stateCreated,
+			"stopped":     stateExited,
+			"stopping":    stateExited,
+		},
+		cpArchive: true,
+	}
+}
+
+// NewNerdctlRuntime returns a `Runtime` based on the `nerdctl` CLI
+// (containerd).
+func NewNerdctlRuntime() Runtime {
+	return &cliRuntime{
+		bin:      nerdctlCmd,
+		rmAction: "rm",
+		states: map[string]string{
+			"unknown": stateDead,
+		},
+		cpArchive: false,
+	}
+}
+
+// DetectRuntime returns a CLI based `Runtime` for the first engine found in
+// the PATH, looking for `docker`, `podman` and `nerdctl` in that order.
+func DetectRuntime() (Runtime, error) {
+	candidates := []struct {
+		bin        string
+		newRuntime func() Runtime
+	}{
+		{bin: dockerCmd, newRuntime: NewDockerRuntime},
+		{bin: podmanCmd, newRuntime: NewPodmanRuntime},
+		{bin: nerdctlCmd, newRuntime: NewNerdctlRuntime},
+	}
+
+	for i := range candidates {
+		if _, err := exec.LookPath(candidates[i].bin); err == nil {
+			return candidates[i].newRuntime(), nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"unable to find a container engine, tried: %s, %s, %s",
+		dockerCmd,
+		podmanCmd,
+		nerdctlCmd,
+	)
+}
+
+func (r *cliRuntime) Create(ctx context.Context, cfg RunConfig) (string, error) {
+	return createContainer(ctx, r.bin, cfg)
+}
+
+func (r *cliRuntime) Start(ctx context.Context, id string) error {
+	return startContainer(ctx, r.bin, id)
+}
+
+func (r *cliRuntime) State(ctx context.Context, id string) (string, error) {
+	state, err := getState(ctx, r.bin, id)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := r.states[state]; ok {
+		return s, nil
+	}
+
+	return state, nil
+}
+
+func (r *cliRuntime) Ports(ctx context.Context, id string) (map[uint][]string, error) {
+	return getPublishedPorts(ctx, r.bin, id)
+}
+
+func (r *cliRuntime) Logs(
+	ctx context.Context,
+	id string,
+	opts LogOptions,
+) (string, error) {
+	return getLogs(ctx, r.bin, id, opts)
+}
+
+func (r *cliRuntime) FollowLogs(
+	ctx context.Context,
+	id string,
+	opts LogOptions,
+	stdOut io.Writer,
+	stdErr io.Writer,
+) error {
+	return followLogs(ctx, r.bin, id, opts, stdOut, stdErr)
+}
+
+func (r *cliRuntime) Exec(
+	ctx context.Context,
+	id string,
+	cmd []string,
+	opts ExecOptions,
+) (int, error) {
+	return dockerExecute(ctx, r.bin, id, cmd, opts)
+}
+
+func (r *cliRuntime) Inspect(ctx context.Context, id string) (string, error) {
+	return inspectContainer(ctx, r.bin, id)
+}
+
+func (r *cliRuntime) Health(ctx context.Context, id string) (Health, error) {
+	return getHealth(ctx, r.bin, id)
+}
+
+func (r *cliRuntime) CopyTo(
+	ctx context.Context,
+	id string,
+	dir string,
+	archive io.Reader,
+) error {
+	if r.cpArchive {
+		return copyArchiveTo(ctx, r.bin, id, dir, archive)
+	}
+
+	return copyDirTo(ctx, r.bin, id, dir, archive)
+}
+
+func (r *cliRuntime) CopyFrom(
+	ctx context.Context,
+	id string,
+	path string,
+) (io.ReadCloser, error) {
+	if r.cpArchive {
+		return copyArchiveFrom(ctx, r.bin, id, path)
+	}
+
+	return copyDirFrom(ctx, r.bin, id, path)
+}
+
+func (r *cliRuntime) Stop(ctx context.Context, id string) error {
+	return stopContainer(ctx, r.bin, id)
+}
+
+func (r *cliRuntime) Find(
+	ctx context.Context,
+	labels map[string]string,
+) ([]string, error) {
+	return findContainers(ctx, r.bin, labels)
+}
+
+func (r *cliRuntime) Pause(ctx context.Context, id string) error {
+	return containerAction(ctx, r.bin, "pause", id)
+}
+
+func (r *cliRuntime) Unpause(ctx context.Context, id string) error {
+	return containerAction(ctx, r.bin, "unpause", id)
+}
+
+func (r *cliRuntime) Restart(
+	ctx context.Context,
+	id string,
+	timeout time.Duration,
+) error {
+	return containerAction(
+		ctx,
+		r.bin,
+		"restart",
+		"-t",
+		timeoutSeconds(timeout),
+		id,
+	)
+}
+
+// timeoutSeconds returns the timeout in whole seconds, the engines do not
+// support fractions. It rounds up, since a timeout of 0 kills immediately.
+func timeoutSeconds(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "0"
+	}
+
+	return strconv.FormatInt(
+		int64((timeout+time.Second-1)/time.Second),
+		base10,
+	)
+}
+
+func (r *cliRuntime) Kill(
+	ctx context.Context,
+	id string,
+	signal syscall.Signal,
+) error {
+	return containerAction(ctx, r.bin, "kill", "-s", strconv.Itoa(int(signal)), id)
+}
+
+func (r *cliRuntime) Remove(ctx context.Context, id string) error {
+	return removeContainer(ctx, r.bin, r.rmAction, id)
+}
+
+func (r *cliRuntime) Volumes(ctx context.Context, id string) ([]string, error) {
+	return getVolumes(ctx, r.bin, id)
+}
+
+func (r *cliRuntime) RemoveVolumes(ctx context.Context, names []string) error {
+	return deleteVolumes(ctx, r.bin, names)
+}
+
+func (r *cliRuntime) CreateNetwork(
+	ctx context.Context,
+	name string,
+	labels map[string]string,
+) error {
+	return createNetwork(ctx, r.bin, name, labels)
+}
+
+func (r *cliRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	return removeNetwork(ctx, r.bin, name)
+}
+
+func (r *cliRuntime) ConnectNetwork(
+	ctx context.Context,
+	network string,
+	id string,
+	aliases []string,
+) error {
+	return connectNetwork(ctx, r.bin, network, id, aliases)
+}
+
+func (r *cliRuntime) DisconnectNetwork(
+	ctx context.Context,
+	network string,
+	id string,
+) error {
+	return disconnectNetwork(ctx, r.bin, network, id)
+}
+
+func (r *cliRuntime) IPAddress(
+	ctx context.Context,
+	id string,
+	network string,
+) (string, error) {
+	return getIPAddress(ctx, r.bin, id, network)
+}
+
+func (r *cliRuntime) Build(ctx context.Context, cfg BuildConfig, w io.Writer) error {
+	return buildImage(ctx, r.bin, cfg, w)
+}
+
+func (r *cliRuntime) RemoveImage(ctx context.Context, ref string) error {
+	return removeImage(ctx, r.bin, ref)
+}
+
+func (r *cliRuntime) Pull(ctx context.Context, ref string, w io.Writer) error {
+	return pullImage(ctx, r.bin, ref, w)
+}
+
+func (r *cliRuntime) ImageExists(ctx context.Context, ref string) (bool, error) {
+	return imageExists(ctx, r.bin, ref)
+}
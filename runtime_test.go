@@ -0,0 +1,191 @@
+package dft_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+)
+
+// stubEngine puts an executable shell script named bin onto an otherwise
+// empty PATH, standing in for a container engine CLI.
+// The script only has the shell builtins and dir (for state files) at hand.
+func stubEngine(t *testing.T, bin string, script string) (dir string) {
+	t.Helper()
+
+	return stubEngines(t, []string{bin}, script)
+}
+
+// stubEngines is `stubEngine` for several engines sharing the script
+func stubEngines(t *testing.T, bins []string, script string) (dir string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub engines are shell scripts")
+	}
+
+	dir = t.TempDir()
+
+	for _, bin := range bins {
+		err := os.WriteFile(
+			filepath.Join(dir, bin),
+			[]byte(fmt.Sprintf("#!/bin/sh\ndir=%q\n%s", dir, script)),
+			0o755,
+		)
+		if err != nil {
+			t.Fatalf("[os.WriteFile] unexpected error: %v", err)
+		}
+	}
+
+	t.Setenv("PATH", dir)
+	// the reaper would outlive the test and call the stub
+	t.Setenv("DFT_REAPER", "0")
+
+	return dir
+}
+
+func TestDetectRuntime(tt *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bins []string
+		want string
+	}{
+		{name: "it prefers docker", bins: []string{"nerdctl", "podman", "docker"}, want: "docker"},
+		{name: "it falls back to podman", bins: []string{"nerdctl", "podman"}, want: "podman"},
+		{name: "it falls back to nerdctl", bins: []string{"nerdctl"}, want: "nerdctl"},
+		{name: "it fails without an engine", bins: nil, want: ""},
+	} {
+		tt.Run(
+			tc.name,
+			func(t *testing.T) {
+				// every engine reports its name on inspect
+				stubEngines(t, tc.bins, `echo "${0##*/}"`)
+
+				rt, err := dft.DetectRuntime()
+				if tc.want == "" {
+					if err == nil {
+						t.Error("[dft.DetectRuntime] expected error without an engine")
+					}
+
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("[dft.DetectRuntime] unexpected error: %v", err)
+				}
+
+				out, err := rt.Inspect(context.Background(), "0123456789ab")
+				if err != nil || strings.TrimSpace(out) != tc.want {
+					t.Errorf("[dft.DetectRuntime] unexpected engine: %q, %v", out, err)
+				}
+			},
+		)
+	}
+}
+
+func TestRuntimeStates(tt *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		newRuntime func() dft.Runtime
+		bin        string
+		state      string
+		want       string
+	}{
+		{name: "podman configured", newRuntime: dft.NewPodmanRuntime, bin: "podman", state: "configured", want: "created"},
+		{name: "podman initialized", newRuntime: dft.NewPodmanRuntime, bin: "podman", state: "initialized", want: "created"},
+		{name: "podman stopping", newRuntime: dft.NewPodmanRuntime, bin: "podman", state: "stopping", want: "exited"},
+		{name: "podman stopped", newRuntime: dft.NewPodmanRuntime, bin: "podman", state: "stopped", want: "exited"},
+		{name: "podman running", newRuntime: dft.NewPodmanRuntime, bin: "podman", state: "running", want: "running"},
+		{name: "nerdctl unknown", newRuntime: dft.NewNerdctlRuntime, bin: "nerdctl", state: "unknown", want: "dead"},
+		{name: "nerdctl paused", newRuntime: dft.NewNerdctlRuntime, bin: "nerdctl", state: "paused", want: "paused"},
+		{name: "docker unknown", newRuntime: dft.NewDockerRuntime, bin: "docker", state: "unknown", want: "unknown"},
+	} {
+		tt.Run(
+			"it maps "+tc.name,
+			func(t *testing.T) {
+				stubEngine(t, tc.bin, `
+if [ "$1" = inspect ] && [ "$3" = "{{.State.Status}}" ]; then
+	echo "$STUB_STATE"
+fi
+`)
+				t.Setenv("STUB_STATE", tc.state)
+
+				state, err := tc.newRuntime().State(context.Background(), "0123456789ab")
+				if err != nil || state != tc.want {
+					t.Errorf("[rt.State] unexpected state: %q, %v", state, err)
+				}
+			},
+		)
+	}
+}
+
+func TestNerdctlCopy(tt *testing.T) {
+	// nerdctl can not stream archives, the stub only copies
+	// from and into directories
+	dir := stubEngine(tt, "nerdctl", `
+case "$1" in
+create) echo 0123456789abcdef0123 ;;
+inspect) if [ "$3" = "{{.State.Status}}" ]; then echo running; fi ;;
+cp)
+	if [ "$2" = - ] || [ "$3" = - ]; then
+		echo "unsupported" >&2
+		exit 1
+	fi
+
+	case "$2" in
+	0123456789ab:*) echo "${2#*:}" > "$3" ;;
+	*)
+		read -r line < "${2%.}app.yaml"
+		echo "$line" > "$dir/app.yaml"
+		echo "$3" > "$dir/target"
+		;;
+	esac
+	;;
+esac
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := dft.StartContainer(ctx, "nginx", dft.WithRuntime(dft.NewNerdctlRuntime()))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	tt.Run(
+		"it copies files into the container through a directory",
+		func(t *testing.T) {
+			err := c.WriteFile(ctx, "/etc/app.yaml", []byte("listen: 80\n"), 0o644)
+			if err != nil {
+				t.Fatalf("[ctr.WriteFile] unexpected error: %v", err)
+			}
+
+			b, _ := os.ReadFile(filepath.Join(dir, "app.yaml"))
+			if string(b) != "listen: 80\n" {
+				t.Errorf("[ctr.WriteFile] unexpected content: %q", b)
+			}
+
+			b, _ = os.ReadFile(filepath.Join(dir, "target"))
+			if string(b) != "0123456789ab:/etc\n" {
+				t.Errorf("[ctr.WriteFile] unexpected target: %q", b)
+			}
+		},
+	)
+
+	tt.Run(
+		"it copies files out of the container through a directory",
+		func(t *testing.T) {
+			b, err := c.ReadFile(ctx, "/run/report.txt")
+			if err != nil || string(b) != "/run/report.txt\n" {
+				t.Errorf("[ctr.ReadFile] unexpected content: %q, %v", b, err)
+			}
+		},
+	)
+}
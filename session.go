@@ -0,0 +1,114 @@
+package dft
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// envReaper disables the session reaper if set to "0"
+const envReaper = "DFT_REAPER"
+
+var (
+	session = randomSuffix()
+
+	reapersMu sync.Mutex
+	reapers   = map[string]bool{}
+)
+
+// SessionID returns the id of the current test process.
+// All containers and networks created by dft are labeled with
+// "dft.session=<id>", e.g. to find them via
+// `docker ps --filter label=dft.session=<id>`.
+func SessionID() string {
+	return session
+}
+
+// sessionLabels returns the labels every resource of this process gets
+func sessionLabels() map[string]string {
+	return map[string]string{labelSession: session}
+}
+
+// reaper is implemented by runtimes that can remove the resources of a
+// session from a shell script, without the test process
+type reaper interface {
+	// reapCommand returns the command prefix used inside of the script,
+	// false if the runtime can not be reaped
+	reapCommand() (string, bool)
+}
+
+// watchSession starts a detached watcher once per engine, which removes all
+// containers (including their anonymous volumes), networks and built images
+// of this session after the test process is gone, e.g. because `go test` was killed
+// by a timeout or Ctrl-C and the deferred `Stop` calls never ran.
+//
+// The watcher is best effort, failing to start it does not fail the caller.
+func watchSession(rt Runtime) {
+	if os.Getenv(envReaper) == "0" {
+		return
+	}
+
+	r, ok := rt.(reaper)
+	if !ok {
+		return
+	}
+
+	bin, ok := r.reapCommand()
+	if !ok {
+		return
+	}
+
+	reapersMu.Lock()
+	defer reapersMu.Unlock()
+
+	if reapers[bin] {
+		return
+	}
+
+	if err := startReaper(bin, os.Getpid()); err != nil {
+		return
+	}
+
+	reapers[bin] = true
+}
+
+// startReaper starts the detached script removing the resources of the
+// session once the process is gone
+func startReaper(bin string, pid int) error {
+	filter := "label=" + labelSession + "=" + session
+	imageFilter := "label=" + labelBuildSession + "=" + session
+
+	// INFO: images go last, they can not be removed while containers use them
+	script := fmt.Sprintf(
+		`while kill -0 %d 2>/dev/null; do sleep 1; done
+ids=$(%[2]s ps -aq --filter %[3]s)
+[ -n "$ids" ] && %[2]s rm -f -v $ids
+nets=$(%[2]s network ls -q --filter %[3]s)
+[ -n "$nets" ] && %[2]s network rm $nets
+imgs=$(%[2]s image ls -q --filter %[4]s)
+[ -n "$imgs" ] && %[2]s image rm -f $imgs
+exit 0`,
+		pid,
+		bin,
+		filter,
+		imageFilter,
+	)
+
+	// INFO: stdio stays unset (/dev/null), otherwise `go test` waits for the
+	// watcher to close its output before it exits
+	return startDetached(exec.Command("sh", "-c", script))
+}
+
+func (r *cliRuntime) reapCommand() (string, bool) {
+	return r.bin, true
+}
+
+func (r *apiRuntime) reapCommand() (string, bool) {
+	// INFO: the script needs a CLI, the engine is the same one we talk to
+	if _, err := exec.LookPath(dockerCmd); err != nil {
+		return "", false
+	}
+
+	return dockerCmd + " -H " + r.host, true
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package dft
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// startDetached is not supported, containers of killed test runs
+// have to be removed by label
+func startDetached(_ *exec.Cmd) error {
+	return errors.New("detached processes are not supported")
+}
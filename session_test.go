@@ -0,0 +1,116 @@
+package dft_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestSession(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := dfttest.NewRuntime()
+	label := "dft.session=" + dft.SessionID()
+
+	n, err := dft.CreateNetwork(ctx, "", dft.WithNetworkRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.CreateNetwork] unexpected error: %v", err)
+	}
+
+	c, err := dft.StartContainer(ctx, "postgres", dft.WithNetwork(n), dft.WithRuntime(rt))
+	if err != nil {
+		tt.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+	}
+	defer c.Stop(ctx)
+
+	tt.Run(
+		"it labels networks with the session",
+		func(t *testing.T) {
+			for _, call := range rt.Calls() {
+				if call.Method == "CreateNetwork" &&
+					(len(call.Args) != 2 || call.Args[1] != label) {
+					t.Errorf("[dft.CreateNetwork] unexpected labels: %v", call.Args)
+				}
+			}
+		},
+	)
+
+	tt.Run(
+		"it labels containers with the session",
+		func(t *testing.T) {
+			cfg, _ := rt.Config(rt.Containers()[0])
+
+			if cfg.Labels["dft.session"] != dft.SessionID() {
+				t.Errorf("[dft.StartContainer] unexpected labels: %v", cfg.Labels)
+			}
+		},
+	)
+}
+
+func TestSessionReaper(t *testing.T) {
+	// the reaper needs sh and sleep next to the stub
+	path := os.Getenv("PATH")
+
+	dir := stubEngine(t, "docker", `
+echo "$*" >> "$dir/calls"
+
+case "$1" in
+ps) echo 0123456789ab ;;
+network|image) if [ "$2" = ls ]; then echo "$1-1"; fi ;;
+esac
+`)
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	// a short lived process stands in for the test binary
+	cmd := exec.Command("sleep", "0.5")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("unable to run sleep: %v", err)
+	}
+
+	if err := dft.StartReaper("docker", cmd.Process.Pid); err != nil {
+		t.Fatalf("[dft.StartReaper] unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "calls")); err == nil {
+		t.Error("[dft.StartReaper] resources were removed while the process was alive")
+	}
+
+	_ = cmd.Wait()
+
+	filter := "--filter label=dft.session=" + dft.SessionID()
+	want := []string{
+		"ps -aq " + filter,
+		"rm -f -v 0123456789ab",
+		"network ls -q " + filter,
+		"network rm network-1",
+		"image ls -q --filter label=dft.build.session=" + dft.SessionID(),
+		"image rm -f image-1",
+	}
+
+	var calls []string
+
+	// the reaper checks the process once per second
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		b, _ := os.ReadFile(filepath.Join(dir, "calls"))
+
+		calls = strings.Split(strings.TrimSpace(string(b)), "\n")
+		if len(calls) == len(want) {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("[dft.StartReaper] unexpected calls:\n%s", strings.Join(calls, "\n"))
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package dft
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startDetached starts the command in its own process group,
+// so a SIGINT sent to the group of `go test` does not reach it
+func startDetached(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	return cmd.Process.Release()
+}
@@ -0,0 +1,417 @@
+package dft
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	sharedDir        = "dft-shared"
+	sharedHashLength = 16
+
+	intervalLock = 50
+)
+
+// sharedEntry is the registry of a shared container, stored as JSON
+// next to its lock file
+type sharedEntry struct {
+	ID     string            `json:"id"`
+	Image  string            `json:"image"`
+	Ports  map[uint][]string `json:"ports"`
+	Labels map[string]string `json:"labels"`
+	// Users holds the pid of every process using the container,
+	// once per `Shared` call
+	Users []int `json:"users"`
+}
+
+// sharedRef points to the lock and registry of a shared container
+type sharedRef struct {
+	// name is the hash of the key, containers are labeled with it
+	name     string
+	lock     string
+	registry string
+}
+
+// newSharedRef returns the paths of the lock and registry for the key
+func newSharedRef(key string) (*sharedRef, error) {
+	dir := filepath.Join(os.TempDir(), sharedDir)
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])[:sharedHashLength]
+
+	return &sharedRef{
+		name:     name,
+		lock:     filepath.Join(dir, name+".lock"),
+		registry: filepath.Join(dir, name+".json"),
+	}, nil
+}
+
+// Shared starts a container once for all processes using the same key,
+// e.g. the packages of `go test ./...`, which run as separate processes.
+// Later calls adopt the running container, the options only apply to the
+// first call. `Stop` keeps the container running until the last user
+// stopped it.
+//
+// The processes coordinate through a file lock and a JSON registry in
+// the temp dir. Users that crashed are removed from the registry by the
+// next caller. If all users crashed, e.g. the one starting the container
+// before it was registered, the next caller removes the container
+// (found by the label "dft.shared") and starts a new one.
+// A container that is not running while others use it, e.g. because one
+// of them paused it, is awaited until the context expires.
+func Shared(
+	ctx context.Context,
+	key string,
+	imageName string,
+	opts ...ContainerOption,
+) (*Container, error) {
+	cfg := containerCfg{
+		args:          nil,
+		env:           nil,
+		mounts:        nil,
+		ports:         nil,
+		runtime:       nil,
+		waitFor:       nil,
+		healthCheck:   nil,
+		noHealthCheck: nil,
+		networks:      nil,
+		pullPolicy:    nil,
+		labels:        nil,
+		logConsumers:  nil,
+		files:         nil,
+		capabilities:  nil,
+		reuse:         nil,
+		noSession:     nil,
+	}
+
+	// INFO: only the runtime and the log consumers are needed here,
+	// newContainer parses the options on its own
+	for i := range opts {
+		opts[i](&cfg)
+	}
+
+	rt := cfg.runtime
+	if rt == nil {
+		var err error
+
+		rt, err = defaultRuntime()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ref, err := newSharedRef(key)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", imageName, err)
+	}
+
+	unlock, err := lockFile(ctx, ref.lock)
+	if errors.Is(err, errors.ErrUnsupported) {
+		// INFO: without locks every process gets its own container
+		return newContainer(ctx, imageName, opts...)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", imageName, err)
+	}
+	defer unlock()
+
+	entry, err := ref.read()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", imageName, err)
+	}
+
+	c, err := ref.adopt(ctx, rt, entry, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", imageName, err)
+	}
+
+	adopted := c != nil
+
+	if !adopted {
+		err = ref.purge(ctx, rt)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", imageName, err)
+		}
+
+		// INFO: the container outlives the session, the pending entry lets
+		// the next caller remove it if we crash before it is registered
+		err = ref.write(&sharedEntry{Image: imageName, Users: []int{os.Getpid()}})
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", imageName, err)
+		}
+
+		c, err = newContainer(
+			ctx,
+			imageName,
+			append(
+				opts[:len(opts):len(opts)],
+				withoutSession(),
+				WithLabel(labelShared, ref.name),
+			)...,
+		)
+		if err != nil {
+			_ = os.Remove(ref.registry)
+
+			return nil, err
+		}
+
+		entry = &sharedEntry{
+			ID:     c.id,
+			Image:  imageName,
+			Ports:  c.ports.all(),
+			Labels: c.labels,
+		}
+	} else if cfg.logConsumers != nil {
+		c.followLogs(*cfg.logConsumers)
+	}
+
+	entry.Users = append(entry.Users, os.Getpid())
+
+	err = ref.write(entry)
+	if err != nil {
+		// without the registry entry nobody would stop the container
+		if adopted {
+			c.detach(ctx)
+		} else {
+			_ = c.stop(context.WithoutCancel(ctx))
+		}
+
+		return nil, fmt.Errorf("[%s] %w", imageName, err)
+	}
+
+	c.shared = ref
+
+	return c, nil
+}
+
+// withoutSession keeps the session reaper away from the container,
+// it has to survive the process that started it
+func withoutSession() ContainerOption {
+	return func(cfg *containerCfg) {
+		noSession := true
+		cfg.noSession = &noSession
+	}
+}
+
+// adopt returns the container of the registry if it is still in use,
+// nil if a new one has to be started.
+// A container of live users that is not running, e.g. paused by one of them
+// or restarting, is awaited until the context expires.
+func (ref *sharedRef) adopt(
+	ctx context.Context,
+	rt Runtime,
+	entry *sharedEntry,
+	imageName string,
+) (*Container, error) {
+	// INFO: without users the entry is left over by crashed processes,
+	// the state of the container is unknown. A pending entry (no id)
+	// always is, its starter held the lock until it registered the id.
+	if entry == nil || entry.ID == "" || len(entry.Users) == 0 {
+		return nil, nil
+	}
+
+	t := time.NewTicker(intervalWait * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		state, err := rt.State(ctx, entry.ID)
+		if errors.Is(err, ErrContainerNotFound) {
+			// INFO: the container was removed by hand
+			return nil, nil
+		}
+
+		if err != nil {
+			// the container may still be running, starting a second one
+			// would lose track of it
+			return nil, err
+		}
+
+		if state == stateRunning {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf(
+				"shared container %s is %s: %w",
+				entry.ID,
+				state,
+				ctx.Err(),
+			)
+		case <-t.C:
+		}
+	}
+
+	if entry.Image != imageName {
+		return nil, fmt.Errorf(
+			"shared container %s runs image %s",
+			entry.ID,
+			entry.Image,
+		)
+	}
+
+	return &Container{
+		id:       entry.ID,
+		rt:       rt,
+		ports:    &publishedPorts{m: entry.Ports},
+		restarts: &restarts{},
+		labels:   entry.Labels,
+		procs:    &processes{},
+		proxies:  &proxies{},
+	}, nil
+}
+
+// purge removes all containers of the key that are left over,
+// including their volumes
+func (ref *sharedRef) purge(ctx context.Context, rt Runtime) error {
+	ids, err := rt.Find(ctx, map[string]string{labelShared: ref.name})
+	if err != nil {
+		return err
+	}
+
+	for i := range ids {
+		// INFO: a failed stop fails the removal as well
+		_ = rt.Stop(ctx, ids[i])
+
+		err = removeWithVolumes(ctx, rt, ids[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// release removes the process from the users of the container,
+// the last user stops it
+func (ref *sharedRef) release(ctx context.Context, c Container) error {
+	unlock, err := lockFile(ctx, ref.lock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry, err := ref.read()
+	if err != nil {
+		return err
+	}
+
+	if entry != nil && entry.ID == c.id {
+		if i := slices.Index(entry.Users, os.Getpid()); i >= 0 {
+			entry.Users = slices.Delete(entry.Users, i, i+1)
+		}
+
+		if len(entry.Users) > 0 {
+			c.detach(ctx)
+
+			return ref.write(entry)
+		}
+	}
+
+	err = c.stop(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(ref.registry)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// read returns the registry without users that are gone,
+// nil if there is none
+func (ref *sharedRef) read() (*sharedEntry, error) {
+	b, err := os.ReadFile(ref.registry)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read shared registry: %w", err)
+	}
+
+	var entry sharedEntry
+
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		// INFO: a writer crashed, the container is unknown
+		return nil, nil
+	}
+
+	entry.Users = slices.DeleteFunc(
+		entry.Users,
+		func(pid int) bool {
+			return !processAlive(pid)
+		},
+	)
+
+	return &entry, nil
+}
+
+// write replaces the registry atomically, a crash never leaves
+// a partial file behind
+func (ref *sharedRef) write(entry *sharedEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(ref.registry), "registry-*")
+	if err != nil {
+		return fmt.Errorf("unable to write shared registry: %w", err)
+	}
+
+	_, err = f.Write(b)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), ref.registry)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("unable to write shared registry: %w", err)
+	}
+
+	return nil
+}
+
+// waitLock polls try until it acquired the lock or the context is done
+func waitLock(ctx context.Context, try func() (bool, error)) error {
+	t := time.NewTicker(intervalLock * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		ok, err := try()
+		if err != nil || ok {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("unable to acquire shared lock: %w", ctx.Err())
+		case <-t.C:
+		}
+	}
+}
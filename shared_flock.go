@@ -0,0 +1,50 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package dft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile acquires an exclusive lock on the file, the kernel releases it
+// if the process crashes
+func lockFile(ctx context.Context, path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open shared lock: %w", err)
+	}
+
+	err = waitLock(
+		ctx,
+		func() (bool, error) {
+			err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+			if errors.Is(err, syscall.EWOULDBLOCK) || errors.Is(err, syscall.EINTR) {
+				return false, nil
+			}
+
+			return err == nil, err
+		},
+	)
+	if err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// processAlive reports if a process with the pid exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	// INFO: EPERM means the process exists, but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package dft
+
+import (
+	"context"
+	"errors"
+)
+
+// lockFile is not supported, `Shared` starts a container per process
+func lockFile(_ context.Context, _ string) (func(), error) {
+	return nil, errors.ErrUnsupported
+}
+
+// processAlive can not tell, every user is kept
+func processAlive(_ int) bool {
+	return true
+}
@@ -0,0 +1,345 @@
+package dft_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+// brokenStateRuntime fails every `State` call, like an engine that
+// does not answer in time
+type brokenStateRuntime struct {
+	*dfttest.Runtime
+}
+
+func (rt brokenStateRuntime) State(context.Context, string) (string, error) {
+	return "", context.DeadlineExceeded
+}
+
+func TestShared(tt *testing.T) {
+	newRuntime := func() *dfttest.Runtime {
+		return dfttest.NewRuntime(
+			dfttest.WithPorts(map[uint][]string{5432: {"0.0.0.0:32768"}}),
+			dfttest.WithVolumes("data"),
+		)
+	}
+
+	shared := func(t *testing.T, rt *dfttest.Runtime) *dft.Container {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		c, err := dft.Shared(ctx, "postgres", "postgres", dft.WithRandomPort(5432), dft.WithRuntime(rt))
+		if err != nil {
+			t.Fatalf("[dft.Shared] unexpected error: %v", err)
+		}
+
+		prts, ok := c.ExposedPorts(5432)
+		if !ok || len(prts) != 1 || prts[0] != 32768 {
+			t.Errorf("[ctr.ExposedPorts] unexpected ports: %v", prts)
+		}
+
+		return c
+	}
+
+	tt.Run(
+		"it stops the container with the last user",
+		func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+
+			rt := newRuntime()
+
+			c1 := shared(t, rt)
+			c2 := shared(t, rt)
+
+			if rt.Count("Create") != 1 {
+				t.Fatalf("[dft.Shared] container was created %d times", rt.Count("Create"))
+			}
+
+			cfg, _ := rt.Config(rt.Containers()[0])
+			if _, ok := cfg.Labels["dft.session"]; ok || cfg.Labels["dft.shared"] == "" {
+				t.Errorf("[dft.Shared] unexpected labels: %v", cfg.Labels)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := c1.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 1 {
+				t.Fatal("[ctr.Stop] container was removed while still in use")
+			}
+
+			if err := c2.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Errorf("[ctr.Stop] container was not removed by the last user")
+			}
+
+			if files, _ := filepath.Glob(filepath.Join(os.TempDir(), "dft-shared", "*.json")); len(files) != 0 {
+				t.Errorf("[ctr.Stop] registry was not removed: %v", files)
+			}
+		},
+	)
+
+	// crash rewrites the registry as if the users had crashed,
+	// a finished process stands in for a crashed user
+	crash := func(t *testing.T, rewrite func(entry map[string]any, pid int)) {
+		t.Helper()
+
+		cmd := exec.Command("true")
+		if err := cmd.Run(); err != nil {
+			t.Skipf("unable to run true: %v", err)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(os.TempDir(), "dft-shared", "*.json"))
+		if len(files) != 1 {
+			t.Fatalf("[dft.Shared] unexpected registries: %v", files)
+		}
+
+		b, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatalf("[os.ReadFile] unexpected error: %v", err)
+		}
+
+		var entry map[string]any
+		if err = json.Unmarshal(b, &entry); err != nil {
+			t.Fatalf("[json.Unmarshal] unexpected error: %v", err)
+		}
+
+		rewrite(entry, cmd.Process.Pid)
+
+		b, _ = json.Marshal(entry)
+		if err = os.WriteFile(files[0], b, 0o600); err != nil {
+			t.Fatalf("[os.WriteFile] unexpected error: %v", err)
+		}
+	}
+
+	tt.Run(
+		"it drops users that crashed",
+		func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+
+			rt := newRuntime()
+
+			c1 := shared(t, rt)
+
+			crash(t, func(entry map[string]any, pid int) {
+				entry["users"] = []int{pid, os.Getpid()}
+			})
+
+			c2 := shared(t, rt)
+
+			if rt.Count("Create") != 1 {
+				t.Fatalf("[dft.Shared] container was not adopted (%d created)", rt.Count("Create"))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := c2.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 1 {
+				t.Fatal("[ctr.Stop] container was removed while still in use")
+			}
+
+			if err := c1.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Errorf("[ctr.Stop] container was not removed by the last user")
+			}
+		},
+	)
+
+	for _, tc := range []struct {
+		name    string
+		rewrite func(entry map[string]any, pid int)
+	}{
+		{
+			name: "it replaces the container if all users crashed",
+			rewrite: func(entry map[string]any, pid int) {
+				entry["users"] = []int{pid}
+			},
+		},
+		{
+			name: "it removes the container if its starter crashed before registering it",
+			rewrite: func(entry map[string]any, pid int) {
+				delete(entry, "id")
+				entry["users"] = []int{pid}
+			},
+		},
+		{
+			name: "it removes the container if the registry is corrupt",
+			rewrite: func(entry map[string]any, _ int) {
+				entry["users"] = "garbage"
+			},
+		},
+	} {
+		tt.Run(
+			tc.name,
+			func(t *testing.T) {
+				t.Setenv("TMPDIR", t.TempDir())
+
+				rt := newRuntime()
+
+				shared(t, rt)
+				crash(t, tc.rewrite)
+
+				c := shared(t, rt)
+
+				if rt.Count("Create") != 2 || len(rt.Containers()) != 1 {
+					t.Fatalf(
+						"[dft.Shared] left over container was not replaced (%d created, %d left)",
+						rt.Count("Create"),
+						len(rt.Containers()),
+					)
+				}
+
+				if rt.Count("RemoveVolumes") != 1 {
+					t.Errorf("[dft.Shared] volumes of the left over container were not removed")
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				if err := c.Stop(ctx); err != nil {
+					t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+				}
+
+				if len(rt.Containers()) != 0 {
+					t.Errorf("[ctr.Stop] container was not removed")
+				}
+			},
+		)
+	}
+
+	tt.Run(
+		"it waits for the container of other users to run again",
+		func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+
+			rt := newRuntime()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			c1 := shared(t, rt)
+
+			if err := c1.Pause(ctx); err != nil {
+				t.Fatalf("[ctr.Pause] unexpected error: %v", err)
+			}
+
+			go func() {
+				time.Sleep(300 * time.Millisecond)
+				_ = c1.Unpause(ctx)
+			}()
+
+			c2 := shared(t, rt)
+
+			if rt.Count("Create") != 1 || rt.Count("Stop") != 0 {
+				t.Fatalf(
+					"[dft.Shared] container was replaced (%d created, %d stopped)",
+					rt.Count("Create"),
+					rt.Count("Stop"),
+				)
+			}
+
+			if err := c2.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if err := c1.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Errorf("[ctr.Stop] container was not removed by the last user")
+			}
+		},
+	)
+
+	tt.Run(
+		"it keeps the container of other users if it stays paused",
+		func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+
+			rt := newRuntime()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			c := shared(t, rt)
+
+			if err := c.Pause(ctx); err != nil {
+				t.Fatalf("[ctr.Pause] unexpected error: %v", err)
+			}
+
+			sCtx, sCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+			defer sCancel()
+
+			_, err := dft.Shared(sCtx, "postgres", "postgres", dft.WithRuntime(rt))
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("[dft.Shared] expected timeout, got: %v", err)
+			}
+
+			if rt.Count("Create") != 1 || len(rt.Containers()) != 1 {
+				t.Fatalf("[dft.Shared] container of the other user was replaced")
+			}
+
+			if err = c.Unpause(ctx); err != nil {
+				t.Fatalf("[ctr.Unpause] unexpected error: %v", err)
+			}
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Errorf("[ctr.Stop] container was not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it does not start a second container if the state is unknown",
+		func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+
+			rt := newRuntime()
+			c := shared(t, rt)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err := dft.Shared(ctx, "postgres", "postgres", dft.WithRuntime(brokenStateRuntime{rt}))
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("[dft.Shared] expected state error, got: %v", err)
+			}
+
+			if rt.Count("Create") != 1 {
+				t.Errorf("[dft.Shared] container was created %d times", rt.Count("Create"))
+			}
+
+			if err = c.Stop(ctx); err != nil {
+				t.Fatalf("[ctr.Stop] unexpected error: %v", err)
+			}
+		},
+	)
+}
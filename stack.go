@@ -0,0 +1,297 @@
+package dft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const timeoutStackStop = 30 * time.Second
+
+// StackContainer declares a member of a `Stack`
+type StackContainer struct {
+	// Name identifies the container inside of the stack and is used as its
+	// alias in the stack network
+	Name string
+	// Image is the image the container is started from
+	Image string
+	// Options are passed to `StartContainer`
+	Options []ContainerOption
+	// WaitFor holds the conditions that have to be met before containers
+	// depending on this one are started
+	WaitFor []WaitStrategy
+	// DependsOn lists the names of the containers that have to be ready
+	// before this one is started
+	DependsOn []string
+}
+
+// Stack is a group of containers started in dependency order on a shared
+// network and stopped together
+type Stack struct {
+	network *Network
+
+	mu         sync.Mutex
+	containers map[string]*Container
+	// order holds the names in the order the containers became ready
+	order []string
+}
+
+type stackCfg struct {
+	runtime Runtime
+}
+
+// StackOption configures `StartStack`
+type StackOption func(cfg *stackCfg)
+
+// WithStackRuntime sets the container engine used for the network and all
+// containers of the stack
+// (default: `DetectRuntime()`)
+func WithStackRuntime(rt Runtime) StackOption {
+	return func(cfg *stackCfg) {
+		cfg.runtime = rt
+	}
+}
+
+// errDependency marks containers that were not started because a dependency
+// or a sibling failed
+var errDependency = errors.New("not started")
+
+// StartStack starts all containers on a new network, in parallel where the
+// dependencies allow it.
+// If a container fails, all containers started so far are stopped and the
+// error of the failing container (including its logs) is returned.
+func StartStack(
+	ctx context.Context,
+	members []StackContainer,
+	opts ...StackOption,
+) (*Stack, error) {
+	cfg := stackCfg{
+		runtime: nil,
+	}
+
+	for i := range opts {
+		opts[i](&cfg)
+	}
+
+	err := validateStack(members)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := cfg.runtime
+	if rt == nil {
+		rt, err = defaultRuntime()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	network, err := newNetwork(ctx, rt, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stack{
+		network:    network,
+		containers: make(map[string]*Container, len(members)),
+	}
+
+	sCtx, sCtxCancel := context.WithCancel(ctx)
+	defer sCtxCancel()
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+
+	ready := make(map[string]chan struct{}, len(members))
+	for i := range members {
+		ready[members[i].Name] = make(chan struct{})
+	}
+
+	for i := range members {
+		wg.Add(1)
+
+		go func(m StackContainer) {
+			defer wg.Done()
+
+			c, sErr := s.start(sCtx, rt, m, ready)
+			if sErr != nil {
+				errMu.Lock()
+				if firstErr == nil && !errors.Is(sErr, errDependency) {
+					firstErr = fmt.Errorf("[stack:%s] %w", m.Name, sErr)
+				}
+				errMu.Unlock()
+
+				// no need to start the remaining containers
+				sCtxCancel()
+
+				return
+			}
+
+			s.mu.Lock()
+			s.containers[m.Name] = c
+			s.order = append(s.order, m.Name)
+			s.mu.Unlock()
+
+			close(ready[m.Name])
+		}(members[i])
+	}
+
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+
+	if firstErr != nil {
+		stopCtx, stopCtxCancel := context.WithTimeout(
+			context.Background(),
+			timeoutStackStop,
+		)
+		defer stopCtxCancel()
+
+		_ = s.Stop(stopCtx)
+
+		return nil, firstErr
+	}
+
+	return s, nil
+}
+
+func (s *Stack) start(
+	ctx context.Context,
+	rt Runtime,
+	m StackContainer,
+	ready map[string]chan struct{},
+) (*Container, error) {
+	for _, dep := range m.DependsOn {
+		select {
+		case <-ctx.Done():
+			return nil, errDependency
+		case <-ready[dep]:
+		}
+	}
+
+	opts := append(
+		[]ContainerOption{WithRuntime(rt)},
+		m.Options...,
+	)
+	opts = append(opts, WithNetwork(s.network, m.Name))
+
+	if len(m.WaitFor) > 0 {
+		opts = append(opts, WithWaitStrategy(m.WaitFor...))
+	}
+
+	// INFO: if a sibling fails while we are starting, our error is caused by
+	// the canceled context and ignored since the sibling reported first
+	return StartContainer(ctx, m.Image, opts...)
+}
+
+// validateStack makes sure names are unique and dependencies exist and
+// are free of cycles
+func validateStack(members []StackContainer) error {
+	deps := make(map[string][]string, len(members))
+
+	for i := range members {
+		if members[i].Name == "" {
+			return fmt.Errorf("[stack] container %d (%s) has no name", i, members[i].Image)
+		}
+
+		if _, ok := deps[members[i].Name]; ok {
+			return fmt.Errorf("[stack] duplicate container name %q", members[i].Name)
+		}
+
+		deps[members[i].Name] = members[i].DependsOn
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	marks := make(map[string]int, len(members))
+
+	var visit func(name string, path []string) error
+
+	visit = func(name string, path []string) error {
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("[stack] dependency cycle: %v", append(path, name))
+		case visited:
+			return nil
+		}
+
+		marks[name] = visiting
+
+		for _, dep := range deps[name] {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("[stack] %q depends on unknown container %q", name, dep)
+			}
+
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		marks[name] = visited
+
+		return nil
+	}
+
+	for i := range members {
+		if err := visit(members[i].Name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Container returns the container with the given name
+func (s *Stack) Container(name string) (*Container, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.containers[name]
+
+	return c, ok
+}
+
+// Network returns the network shared by all containers of the stack
+func (s *Stack) Network() *Network {
+	return s.network
+}
+
+// Stop stops all containers in reverse order of their startup
+// and removes the network
+func (s *Stack) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+
+	for i := len(s.order) - 1; i >= 0; i-- {
+		name := s.order[i]
+
+		if err := s.containers[name].Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("[stack:%s] %w", name, err))
+		}
+
+		delete(s.containers, name)
+	}
+
+	s.order = nil
+
+	// the network is gone with the last container,
+	// unless no container was started at all
+	if err := s.network.Remove(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
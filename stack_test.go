@@ -0,0 +1,136 @@
+package dft_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestStack(tt *testing.T) {
+	tt.Run(
+		"it starts containers in dependency order and stops them in reverse",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			s, err := dft.StartStack(
+				ctx,
+				[]dft.StackContainer{
+					{Name: "app", Image: "app", DependsOn: []string{"db", "cache"}},
+					{Name: "db", Image: "postgres"},
+					{Name: "cache", Image: "redis"},
+				},
+				dft.WithStackRuntime(rt),
+			)
+			if err != nil {
+				t.Fatalf("[dft.StartStack] unexpected error: %v", err)
+			}
+
+			var runs []string
+
+			for _, c := range rt.Calls() {
+				if c.Method == "Create" {
+					runs = append(runs, c.Args[0])
+				}
+			}
+
+			if len(runs) != 3 || runs[2] != "app" {
+				t.Errorf("[dft.StartStack] unexpected start order: %v", runs)
+			}
+
+			app, ok := s.Container("app")
+			if !ok {
+				t.Fatal("[stack.Container] app is missing")
+			}
+
+			if _, err = app.IPAddress(ctx, s.Network().Name()); err != nil {
+				t.Errorf("[ctr.IPAddress] app is not in the stack network: %v", err)
+			}
+
+			if err = s.Stop(ctx); err != nil {
+				t.Fatalf("[stack.Stop] unexpected error: %v", err)
+			}
+
+			var stops []string
+
+			for _, c := range rt.Calls() {
+				if c.Method == "Stop" {
+					stops = append(stops, c.ID)
+				}
+			}
+
+			// app was started last and got the third id
+			if len(stops) != 3 || stops[0] != "000000000003" {
+				t.Errorf("[stack.Stop] app was not stopped first: %v", stops)
+			}
+
+			if len(rt.Containers()) != 0 || len(rt.Networks()) != 0 {
+				t.Error("[stack.Stop] resources were not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it stops started containers if a member fails",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(dfttest.WithLogs("FATAL: password authentication failed"))
+
+			_, err := dft.StartStack(
+				ctx,
+				[]dft.StackContainer{
+					{Name: "db", Image: "postgres"},
+					{
+						Name:      "migrate",
+						Image:     "migrate",
+						DependsOn: []string{"db"},
+						// the image has no HEALTHCHECK
+						WaitFor: []dft.WaitStrategy{dft.ForHealthy()},
+					},
+					{Name: "app", Image: "app", DependsOn: []string{"migrate"}},
+				},
+				dft.WithStackRuntime(rt),
+			)
+			if err == nil || !strings.Contains(err.Error(), "[stack:migrate]") {
+				t.Fatalf("[dft.StartStack] unexpected error: %v", err)
+			}
+
+			if !strings.Contains(err.Error(), "password authentication failed") {
+				t.Errorf("[dft.StartStack] error does not contain the logs: %v", err)
+			}
+
+			if rt.Count("Create") != 2 {
+				t.Errorf("[dft.StartStack] dependent container was started")
+			}
+
+			if len(rt.Containers()) != 0 || len(rt.Networks()) != 0 {
+				t.Error("[dft.StartStack] resources were not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it rejects dependency cycles",
+		func(t *testing.T) {
+			_, err := dft.StartStack(
+				context.Background(),
+				[]dft.StackContainer{
+					{Name: "a", Image: "a", DependsOn: []string{"b"}},
+					{Name: "b", Image: "b", DependsOn: []string{"a"}},
+				},
+				dft.WithStackRuntime(dfttest.NewRuntime()),
+			)
+			if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+				t.Errorf("[dft.StartStack] unexpected error: %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,79 @@
+package dft
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const (
+	// timeoutRun limits the startup if the test has no deadline (-timeout 0)
+	timeoutRun = 2 * time.Minute
+	// timeoutCleanup is reserved from the deadline of the test for the
+	// teardown and used as timeout of `Stop`
+	timeoutCleanup = 10 * time.Second
+)
+
+// Run starts a container for the duration of the test.
+//
+// Startup errors fail the test via `t.Fatalf`. The container is stopped
+// and removed via `t.Cleanup`, if the test failed its logs and inspect output
+// are written to the test log before.
+// The startup is limited by the deadline of the test (`go test -timeout`).
+func Run(t testing.TB, imageName string, opts ...ContainerOption) *Container {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout(t))
+	defer cancel()
+
+	c, err := StartContainer(ctx, imageName, opts...)
+	if err != nil {
+		t.Fatalf("[dft.Run] unable to start %s: %v", imageName, err)
+
+		return nil
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeoutCleanup)
+		defer cancel()
+
+		if t.Failed() {
+			l, lErr := c.Logs(ctx)
+			if lErr != nil {
+				l = lErr.Error()
+			}
+
+			i, iErr := c.Inspect(ctx)
+			if iErr != nil {
+				i = iErr.Error()
+			}
+
+			t.Logf("[dft.Run] %s (%s)\nlogs:\n%s\ninspect:\n%s", imageName, c.id, l, i)
+		}
+
+		if sErr := c.Stop(ctx); sErr != nil {
+			t.Errorf("[dft.Run] unable to stop %s (%s): %v", imageName, c.id, sErr)
+		}
+	})
+
+	return c
+}
+
+// runTimeout returns the time left until the deadline of the test,
+// minus the time needed for the teardown
+func runTimeout(t testing.TB) time.Duration {
+	// INFO: only `*testing.T` knows its deadline, not the interface
+	d, ok := t.(interface{ Deadline() (time.Time, bool) })
+	if !ok {
+		return timeoutRun
+	}
+
+	deadline, ok := d.Deadline()
+	if !ok {
+		return timeoutRun
+	}
+
+	// with no time left `StartContainer` fails fast with a clear error
+	// instead of the test binary panicking on its timeout
+	return max(time.Until(deadline)-timeoutCleanup, 0)
+}
@@ -0,0 +1,101 @@
+package dft_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+// recorder captures what `dft.Run` reports to the test
+type recorder struct {
+	testing.TB
+
+	failed   bool
+	logs     []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Cleanup(fn func()) { r.cleanups = append(r.cleanups, fn) }
+
+func (r *recorder) Failed() bool { return r.failed }
+
+func (r *recorder) Logf(format string, args ...any) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+	r.Logf(format, args...)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) { r.Errorf(format, args...) }
+
+func (r *recorder) cleanup() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestRun(tt *testing.T) {
+	tt.Run(
+		"it stops the container once the test is done",
+		func(t *testing.T) {
+			rt := dfttest.NewRuntime()
+
+			t.Run("inner", func(t *testing.T) {
+				c := dft.Run(t, "postgres", dft.WithRuntime(rt))
+				if c == nil {
+					t.Fatal("[dft.Run] no container returned")
+				}
+			})
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[dft.Run] container was not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it dumps logs and inspect output of failed tests",
+		func(t *testing.T) {
+			rt := dfttest.NewRuntime(dfttest.WithLogs("FATAL: role does not exist"))
+			r := &recorder{TB: t}
+
+			_ = dft.Run(r, "postgres", dft.WithRuntime(rt))
+
+			r.failed = true
+			r.cleanup()
+
+			if len(r.logs) != 1 ||
+				!strings.Contains(r.logs[0], "role does not exist") ||
+				!strings.Contains(r.logs[0], `"Image": "postgres"`) {
+				t.Errorf("[dft.Run] unexpected test log: %q", r.logs)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[dft.Run] container was not removed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it fails the test if the container does not start",
+		func(t *testing.T) {
+			rt := dfttest.NewRuntime(dfttest.WithStates(dfttest.StateExited))
+			r := &recorder{TB: t}
+
+			if c := dft.Run(r, "postgres", dft.WithRuntime(rt)); c != nil {
+				t.Error("[dft.Run] unexpected container")
+			}
+
+			if !r.failed || len(r.cleanups) != 0 {
+				t.Errorf("[dft.Run] test was not failed: %q", r.logs)
+			}
+		},
+	)
+}
@@ -0,0 +1,301 @@
+package dft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const timeoutProbe = 2 * time.Second
+
+// ErrUnhealthy is returned if the HEALTHCHECK of a container reports "unhealthy"
+var ErrUnhealthy = errors.New("container is unhealthy")
+
+// finalError marks a condition that will not be met by polling again,
+// `Wait` returns it immediately
+type finalError struct {
+	err error
+}
+
+func (e *finalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *finalError) Unwrap() error {
+	return e.err
+}
+
+func isFinal(err error) bool {
+	var fErr *finalError
+
+	return errors.As(err, &fErr)
+}
+
+// WaitStrategy is a readiness condition of a container.
+//
+// Ready performs a single check and returns nil once the condition is met,
+// otherwise an error describing why it is not (yet) met.
+// Strategies are polled until they are ready or the context expires.
+type WaitStrategy interface {
+	Ready(ctx context.Context, c *Container) error
+}
+
+// WaitStrategyFunc adapts a function to a `WaitStrategy`
+type WaitStrategyFunc func(ctx context.Context, c *Container) error
+
+func (f WaitStrategyFunc) Ready(ctx context.Context, c *Container) error {
+	return f(ctx, c)
+}
+
+// Wait blocks until all strategies are ready or the context expires.
+// On expiry the error contains the last failing condition.
+// Conditions that can not be met anymore (e.g. an unhealthy container)
+// fail immediately.
+func (c *Container) Wait(ctx context.Context, strategies ...WaitStrategy) error {
+	s := All(strategies...)
+
+	t := time.NewTicker(intervalWait * time.Millisecond)
+	defer t.Stop()
+
+	var lastErr error
+
+	for {
+		err := s.Ready(ctx, c)
+		if err == nil {
+			return nil
+		}
+
+		if isFinal(err) {
+			return fmt.Errorf("wait failed: %w", err)
+		}
+
+		// a check interrupted by the expiring context does not tell us
+		// anything, keep the previous reason instead
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf(
+				"wait failed: %w\n\tlast failing condition: %w",
+				ctx.Err(),
+				lastErr,
+			)
+		case <-t.C:
+		}
+	}
+}
+
+// All is ready once every strategy is ready.
+// The error names the first condition that is not met.
+func All(strategies ...WaitStrategy) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		for i := range strategies {
+			if err := strategies[i].Ready(ctx, c); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Any is ready once one of the strategies is ready.
+// The error contains the reasons of all conditions.
+func Any(strategies ...WaitStrategy) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		errs := make([]error, 0, len(strategies))
+		final := 0
+
+		for i := range strategies {
+			err := strategies[i].Ready(ctx, c)
+			if err == nil {
+				return nil
+			}
+
+			if isFinal(err) {
+				final++
+			}
+
+			errs = append(errs, err)
+		}
+
+		err := fmt.Errorf("none of the conditions is met: %w", errors.Join(errs...))
+		if final == len(strategies) {
+			return &finalError{err: err}
+		}
+
+		return err
+	})
+}
+
+// ForPort is ready once a TCP connection to the exposed port can be
+// established from the host
+func ForPort(port uint) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		addrs, ok := c.ExposedPortAddresses(port)
+		if !ok || len(addrs) == 0 {
+			return fmt.Errorf("port %d: not exposed", port)
+		}
+
+		d := net.Dialer{Timeout: timeoutProbe}
+
+		var err error
+
+		for i := range addrs {
+			var conn net.Conn
+
+			conn, err = d.DialContext(ctx, "tcp", addrs[i])
+			if err == nil {
+				_ = conn.Close()
+
+				return nil
+			}
+		}
+
+		return fmt.Errorf("port %d: %w", port, err)
+	})
+}
+
+// ForHTTP is ready once a GET request to the path on the exposed port
+// meets the condition.
+// If metCondition is nil, any 2xx status is accepted.
+func ForHTTP(
+	port uint,
+	path string,
+	metCondition func(code int, body string) bool,
+) WaitStrategy {
+	if metCondition == nil {
+		metCondition = func(code int, _ string) bool {
+			return code >= http.StatusOK && code < http.StatusMultipleChoices
+		}
+	}
+
+	client := &http.Client{Timeout: timeoutProbe}
+
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		addrs, ok := c.ExposedPortAddresses(port)
+		if !ok || len(addrs) == 0 {
+			return fmt.Errorf("http %d%s: port not exposed", port, path)
+		}
+
+		req, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodGet,
+			"http://"+addrs[0]+path,
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("http %d%s: %w", port, path, err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("http %d%s: %w", port, path, err)
+		}
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("http %d%s: %w", port, path, err)
+		}
+
+		if !metCondition(res.StatusCode, string(b)) {
+			return fmt.Errorf(
+				"http %d%s: condition not met (status %d)",
+				port,
+				path,
+				res.StatusCode,
+			)
+		}
+
+		return nil
+	})
+}
+
+// ForLog is ready once the logs contain at least the given number of
+// matches of the pattern
+func ForLog(pattern *regexp.Regexp, occurrences int) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		l, err := c.Logs(ctx)
+		if err != nil {
+			return fmt.Errorf("log %q: %w", pattern, err)
+		}
+
+		n := len(pattern.FindAllStringIndex(l, -1))
+		if n < occurrences {
+			return fmt.Errorf(
+				"log %q: found %d of %d occurrences",
+				pattern,
+				n,
+				occurrences,
+			)
+		}
+
+		return nil
+	})
+}
+
+// ForExec is ready once the command executed inside of the container
+// meets the condition
+func ForExec(
+	cmd []string,
+	metCondition func(stdOut string, stdErr string, code int) bool,
+) WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		res, err := c.Exec(ctx, cmd, ExecOptions{})
+		if err != nil && res.ExitCode == -1 {
+			return fmt.Errorf("exec %q: %w", cmd, err)
+		}
+
+		if !metCondition(res.Stdout, res.Stderr, res.ExitCode) {
+			return fmt.Errorf(
+				"exec %q: condition not met (code %d)\n\tstdErr:%s\n\tstdOut:%s",
+				cmd,
+				res.ExitCode,
+				res.Stderr,
+				res.Stdout,
+			)
+		}
+
+		return nil
+	})
+}
+
+// ForHealthy is ready once the HEALTHCHECK of the container reports "healthy".
+// It fails immediately with `ErrUnhealthy` and the output of the last probes
+// if the container turns "unhealthy", or if the container has no HEALTHCHECK.
+func ForHealthy() WaitStrategy {
+	return WaitStrategyFunc(func(ctx context.Context, c *Container) error {
+		h, err := c.rt.Health(ctx, c.id)
+		if err != nil {
+			return fmt.Errorf("health: %w", err)
+		}
+
+		switch h.Status {
+		case healthHealthy:
+			return nil
+		case healthUnhealthy:
+			return &finalError{
+				err: fmt.Errorf(
+					"health: %w\n\tprobe output:\n%s",
+					ErrUnhealthy,
+					strings.Join(h.Log, "\n"),
+				),
+			}
+		case "":
+			return &finalError{
+				err: errors.New("health: container has no HEALTHCHECK"),
+			}
+		default:
+			return fmt.Errorf("health: status is %q", h.Status)
+		}
+	})
+}
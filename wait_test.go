@@ -0,0 +1,312 @@
+package dft_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abecodes/dft"
+	"github.com/abecodes/dft/dfttest"
+)
+
+func TestWait(tt *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	// a port nobody listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tt.Fatalf("[net.Listen] unexpected error: %v", err)
+	}
+
+	closed := l.Addr().String()
+	l.Close()
+
+	start := func(t *testing.T, opts ...dfttest.Option) *dft.Container {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		rt := dfttest.NewRuntime(
+			append(
+				[]dfttest.Option{
+					dfttest.WithPorts(map[uint][]string{
+						80:   {strings.TrimPrefix(srv.URL, "http://")},
+						5432: {closed},
+					}),
+				},
+				opts...,
+			)...,
+		)
+
+		c, err := dft.StartContainer(
+			ctx,
+			"nginx",
+			dft.WithRandomPort(80),
+			dft.WithRandomPort(5432),
+			dft.WithRuntime(rt),
+		)
+		if err != nil {
+			t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+		}
+
+		return c
+	}
+
+	tt.Run(
+		"it waits for ports, http and logs",
+		func(t *testing.T) {
+			c := start(
+				t,
+				dfttest.WithLogs("ready to accept connections\nready to accept connections\n"),
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			err := c.Wait(
+				ctx,
+				dft.ForPort(80),
+				dft.ForHTTP(80, "/ready", func(code int, body string) bool {
+					return code == http.StatusOK && strings.Contains(body, "ok")
+				}),
+				dft.ForLog(regexp.MustCompile("ready to accept connections"), 2),
+			)
+			if err != nil {
+				t.Errorf("[ctr.Wait] unexpected error: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it reports the last failing condition",
+		func(t *testing.T) {
+			c := start(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			err := c.Wait(
+				ctx,
+				dft.ForHTTP(80, "/ready", nil),
+				dft.ForHTTP(80, "/missing", nil),
+			)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("[ctr.Wait] unexpected error: %v", err)
+			}
+
+			if !strings.Contains(err.Error(), "http 80/missing: condition not met (status 503)") {
+				t.Errorf("[ctr.Wait] error does not name the failing condition: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it is ready if any of the conditions is met",
+		func(t *testing.T) {
+			c := start(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			err := c.Wait(ctx, dft.Any(dft.ForPort(5432), dft.ForPort(80)))
+			if err != nil {
+				t.Errorf("[ctr.Wait] unexpected error: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it waits for the container to become healthy",
+		func(t *testing.T) {
+			c := start(
+				t,
+				dfttest.WithHealth(
+					dft.Health{Status: "starting"},
+					dft.Health{Status: "starting"},
+					dft.Health{Status: "healthy"},
+				),
+				dfttest.WithExec(func(string, []string) (string, string, int, error) {
+					return "accepting connections", "", 0, nil
+				}),
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			err := c.Wait(
+				ctx,
+				dft.ForHealthy(),
+				dft.ForExec(
+					[]string{"pg_isready"},
+					func(stdOut string, _ string, code int) bool {
+						return code == 0 && strings.Contains(stdOut, "accepting")
+					},
+				),
+			)
+			if err != nil {
+				t.Errorf("[ctr.Wait] unexpected error: %v", err)
+			}
+		},
+	)
+}
+
+func TestWaitForHealthy(tt *testing.T) {
+	tt.Run(
+		"it fails fast once the container is unhealthy",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithHealth(
+					dft.Health{Status: "starting"},
+					dft.Health{
+						Status: "unhealthy",
+						Log:    []string{"pg_isready: no response"},
+					},
+				),
+			)
+
+			_, err := dft.StartContainer(
+				ctx,
+				"postgres",
+				dft.WithHealthCheck("pg_isready", time.Second, time.Second, 3, 0),
+				dft.WithWaitForHealthy(),
+				dft.WithRuntime(rt),
+			)
+			if !errors.Is(err, dft.ErrUnhealthy) {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if ctx.Err() != nil {
+				t.Error("[dft.StartContainer] did not fail fast")
+			}
+
+			if !strings.Contains(err.Error(), "pg_isready: no response") {
+				t.Errorf("[dft.StartContainer] error does not contain probe output: %v", err)
+			}
+
+			if len(rt.Containers()) != 0 {
+				t.Error("[dft.StartContainer] unhealthy container was not removed")
+			}
+		},
+	)
+}
+
+func TestWaitForLog(tt *testing.T) {
+	pattern := regexp.MustCompile("database system is ready to accept connections")
+
+	tt.Run(
+		"it waits for the log line to appear twice",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithLogs("database system is ready to accept connections\n"),
+			)
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				_ = rt.WriteLog(rt.Containers()[0], "restarting\n")
+				_ = rt.WriteLog(rt.Containers()[0], "database system is ready to accept connections\n")
+			}()
+
+			if err = c.WaitForLog(ctx, pattern, 2); err != nil {
+				t.Errorf("[ctr.WaitForLog] unexpected error: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it keeps the lines of stdout and stderr apart",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			id := rt.Containers()[0]
+			_ = rt.WriteLog(id, "database system is ")
+			_ = rt.WriteErrLog(id, "WARNING: no password set\n")
+			_ = rt.WriteLog(id, "ready to accept connections\n")
+
+			if err = c.WaitForLog(ctx, pattern, 1); err != nil {
+				t.Errorf("[ctr.WaitForLog] unexpected error: %v", err)
+			}
+		},
+	)
+
+	tt.Run(
+		"it returns right away without occurrences",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			rt := dfttest.NewRuntime()
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			if err = c.WaitForLog(ctx, pattern, 0); err != nil {
+				t.Errorf("[ctr.WaitForLog] unexpected error: %v", err)
+			}
+
+			if rt.Count("FollowLogs") != 0 {
+				t.Errorf("[ctr.WaitForLog] logs were followed")
+			}
+		},
+	)
+
+	tt.Run(
+		"it includes the tail of the logs on timeout",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			rt := dfttest.NewRuntime(
+				dfttest.WithLogs("initdb: error: directory exists but is not empty\n"),
+			)
+
+			c, err := dft.StartContainer(ctx, "postgres", dft.WithRuntime(rt))
+			if err != nil {
+				t.Fatalf("[dft.StartContainer] unexpected error: %v", err)
+			}
+
+			err = c.WaitForLog(ctx, pattern, 1)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("[ctr.WaitForLog] unexpected error: %v", err)
+			}
+
+			if !strings.Contains(err.Error(), "directory exists but is not empty") {
+				t.Errorf("[ctr.WaitForLog] error does not contain the logs: %v", err)
+			}
+		},
+	)
+}